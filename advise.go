@@ -0,0 +1,131 @@
+package fastcdc
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"slices"
+)
+
+// Goals are the targets of Advise. The zero value sets no target.
+type Goals struct {
+	// MinDedupRatio is the minimum dedup ratio over the sample.
+	MinDedupRatio float64
+	// MaxOverheadPerTB is the maximum size of the chunk metadata per
+	// terabyte (10^12 bytes) of logical data.
+	MaxOverheadPerTB int64
+	// MaxChunks is the maximum number of chunks of the sample.
+	MaxChunks int64
+	// ChunkMetadataSize is the size of the metadata of a chunk, like its
+	// digest and its position in an index. Default is 48 bytes, a SHA-256
+	// digest with its offset and length.
+	ChunkMetadataSize int
+	// MinAvgSize and MaxAvgSize bound the average chunk sizes searched.
+	// Default is 1 KiB to 256 KiB.
+	MinAvgSize uint
+	MaxAvgSize uint
+}
+
+// Advice is a configuration evaluated by Advise over the sample.
+type Advice struct {
+	Estimation
+	// OverheadPerTB is the size of the chunk metadata per terabyte of
+	// logical data.
+	OverheadPerTB float64
+	// StoredBytes is the size of the unique chunks plus the metadata of
+	// all the chunks, what storing the sample would cost.
+	StoredBytes int64
+	// MeetsGoals reports whether the configuration reaches every goal.
+	MeetsGoals bool
+}
+
+// Option returns the chunk sizes option of the advice.
+func (a Advice) Option() Option {
+	return WithChunksSize(a.MinSize, a.AvgSize, a.MaxSize)
+}
+
+// sizeShapes are the min and max sizes searched for an average size, as
+// divisor and multiplier: the shape of the presets, and tighter ones.
+var sizeShapes = [][2]uint{{4, 8}, {4, 4}, {2, 2}}
+
+// Advise searches the chunk sizes for the sample of data in fsys, with
+// the given goals. It evaluates the power of two average sizes between
+// the goals bounds, each with several min and max sizes, like Estimate.
+// The other options, like the algorithm or the key, apply to every
+// configuration, and must not set the chunk sizes.
+//
+// Advise returns every configuration evaluated, the ones which meet the
+// goals first, then by increasing stored bytes. It returns
+// ErrEmptySample if the sample has no data.
+func Advise(fsys fs.FS, goals Goals, opts ...Option) ([]Advice, error) {
+	if goals.ChunkMetadataSize == 0 {
+		goals.ChunkMetadataSize = 48
+	}
+	if goals.MinAvgSize == 0 {
+		goals.MinAvgSize = 1 << 10
+	}
+	if goals.MaxAvgSize == 0 {
+		goals.MaxAvgSize = 256 << 10
+	}
+	if goals.MinAvgSize > goals.MaxAvgSize {
+		return nil, fmt.Errorf("the average sizes searched must be from %d to %d: %w", goals.MinAvgSize, goals.MaxAvgSize, ErrInvalidChunkSize)
+	}
+
+	var configs [][]Option
+	for _, sizes := range goals.chunkSizes() {
+		configs = append(configs, append(slices.Clone(opts), WithChunksSize(sizes[0], sizes[1], sizes[2])))
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no power of two average size from %d to %d: %w", goals.MinAvgSize, goals.MaxAvgSize, ErrInvalidChunkSize)
+	}
+
+	estimations, err := Estimate(fsys, configs...)
+	if err != nil {
+		return nil, err
+	}
+	if estimations[0].Bytes == 0 {
+		return nil, ErrEmptySample
+	}
+
+	advices := make([]Advice, len(estimations))
+	for i, e := range estimations {
+		metadata := e.Chunks * int64(goals.ChunkMetadataSize)
+		a := Advice{
+			Estimation:    e,
+			OverheadPerTB: float64(metadata) / float64(e.Bytes) * 1e12,
+			StoredBytes:   e.UniqueBytes + metadata,
+		}
+		a.MeetsGoals = (goals.MinDedupRatio == 0 || e.DedupRatio() >= goals.MinDedupRatio) &&
+			(goals.MaxOverheadPerTB == 0 || a.OverheadPerTB <= float64(goals.MaxOverheadPerTB)) &&
+			(goals.MaxChunks == 0 || e.Chunks <= goals.MaxChunks)
+		advices[i] = a
+	}
+
+	slices.SortStableFunc(advices, func(a, b Advice) int {
+		if a.MeetsGoals != b.MeetsGoals {
+			if a.MeetsGoals {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.StoredBytes, b.StoredBytes), cmp.Compare(a.Chunks, b.Chunks))
+	})
+	return advices, nil
+}
+
+// chunkSizes returns the min, avg and max sizes searched by Advise. The
+// min and max sizes of a shape are clamped to the bounds of NewChunker.
+func (g Goals) chunkSizes() [][3]uint {
+	var sizes [][3]uint
+	for avg := uint(AverageMin); avg <= min(g.MaxAvgSize, AverageMax); avg <<= 1 {
+		if avg < g.MinAvgSize {
+			continue
+		}
+		for _, shape := range sizeShapes {
+			minSize := min(max(avg/shape[0], MinimumMin), MinimumMax)
+			maxSize := min(max(avg*shape[1], MaximumMin), MaximumMax)
+			sizes = append(sizes, [3]uint{minSize, avg, maxSize})
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,97 @@
+package fastcdc
+
+import (
+	"errors"
+	"math/bits"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAdvise(t *testing.T) {
+	base := randomData(43, 1<<20)
+	edited := slices.Concat(base[:300_000], randomData(44, 100), base[300_000:])
+	fsys := fstest.MapFS{
+		"base":   {Data: base},
+		"edited": {Data: edited},
+	}
+
+	advices, err := Advise(fsys, Goals{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1 KiB to 256 KiB, with 3 shapes each.
+	if len(advices) != 9*len(sizeShapes) {
+		t.Fatalf("advices: want = %d, got = %d", 9*len(sizeShapes), len(advices))
+	}
+	for i, a := range advices {
+		if !a.MeetsGoals {
+			t.Errorf("%v: want to meet empty goals", a.Option())
+		}
+		if _, err := NewChunker(a.Option()); err != nil {
+			t.Errorf("advice %d: %s", i, err)
+		}
+		if a.StoredBytes != a.UniqueBytes+a.Chunks*48 {
+			t.Errorf("stored bytes: want = %d, got = %d", a.UniqueBytes+a.Chunks*48, a.StoredBytes)
+		}
+		if i > 0 && advices[i-1].StoredBytes > a.StoredBytes {
+			t.Errorf("advice %d: stored bytes are not increasing", i)
+		}
+	}
+
+	// A tight metadata budget rules out the small chunks.
+	goals := Goals{MaxOverheadPerTB: 2_000_000_000, MinDedupRatio: 1.5}
+	advices, err = Advise(fsys, goals, WithAlgorithm(FastCDC2020))
+	if err != nil {
+		t.Fatal(err)
+	}
+	met := 0
+	for i, a := range advices {
+		if a.MeetsGoals {
+			met++
+			if met != i+1 {
+				t.Errorf("advice %d meets the goals after one which does not", i)
+			}
+			if a.OverheadPerTB > float64(goals.MaxOverheadPerTB) || a.DedupRatio() < goals.MinDedupRatio {
+				t.Errorf("advice %d: overhead %f and ratio %f miss the goals", i, a.OverheadPerTB, a.DedupRatio())
+			}
+		} else if a.OverheadPerTB <= float64(goals.MaxOverheadPerTB) && a.DedupRatio() >= goals.MinDedupRatio {
+			t.Errorf("advice %d: overhead %f and ratio %f meet the goals", i, a.OverheadPerTB, a.DedupRatio())
+		}
+	}
+	if met == 0 || met == len(advices) {
+		t.Errorf("advices meeting the goals: want some, got = %d of %d", met, len(advices))
+	}
+}
+
+func TestAdviseErrors(t *testing.T) {
+	_, err := Advise(fstest.MapFS{"empty": {}}, Goals{})
+	if !errors.Is(err, ErrEmptySample) {
+		t.Errorf("want = %s, got = %s", ErrEmptySample, err)
+	}
+
+	fsys := fstest.MapFS{"data": {Data: randomData(45, 1000)}}
+	for _, goals := range []Goals{{MinAvgSize: 4096, MaxAvgSize: 1024}, {MinAvgSize: 3000, MaxAvgSize: 4000}} {
+		if _, err := Advise(fsys, goals); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Errorf("want = %s, got = %s", ErrInvalidChunkSize, err)
+		}
+	}
+}
+
+// TestAdviseSizeBounds checks that the sizes searched up to AverageMax
+// are all accepted by NewChunker, without allocating their buffers.
+func TestAdviseSizeBounds(t *testing.T) {
+	sizes := Goals{MinAvgSize: AverageMin, MaxAvgSize: AverageMax}.chunkSizes()
+	if want := 3 * (bits.Len(AverageMax) - bits.Len(AverageMin) + 1); len(sizes) != want {
+		t.Fatalf("sizes: want = %d, got = %d", want, len(sizes))
+	}
+	for _, s := range sizes {
+		minSize, avg, maxSize := s[0], s[1], s[2]
+		if minSize < MinimumMin || minSize > MinimumMax || avg < AverageMin || avg > AverageMax || maxSize < MaximumMin || maxSize > MaximumMax {
+			t.Errorf("sizes %v: out of the chunk size bounds", s)
+		}
+		if minSize >= avg || maxSize <= avg || maxSize-minSize <= avg {
+			t.Errorf("sizes %v: invalid proportions", s)
+		}
+	}
+}
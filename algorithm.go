@@ -0,0 +1,197 @@
+package fastcdc
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// Algorithm is a cut point algorithm of the chunker.
+type Algorithm uint8
+
+const (
+	// FastCDC2016 is the gear-based algorithm of the 2016 FastCDC paper,
+	// with the normalized chunking and the adaptive normal size of
+	// ronomon/deduplication. It is the default algorithm.
+	FastCDC2016 Algorithm = iota
+	// FastCDC2020 is the "rolling two bytes" algorithm of the 2020 FastCDC
+	// paper (Xia et al., IEEE TPDS). The gear hash is shifted left, its
+	// masks are spread over the upper bits and it rolls two bytes per
+	// iteration, which makes it faster than FastCDC2016. It produces
+	// different chunks than FastCDC2016.
+	// https://ieeexplore.ieee.org/document/9055082
+	FastCDC2020
+	// AE is the hash-less Asymmetric Extremum algorithm (Zhang et al.,
+	// IEEE INFOCOM 2015). After the min size, the chunk is cut when the
+	// maximum 8-byte value seen so far is not exceeded for a fixed number of
+	// bytes. It gives a lower chunk size variance than the gear-based
+	// algorithms.
+	// https://ieeexplore.ieee.org/document/7218510
+	AE
+	// RAM is the hash-less Rapid Asymmetric Maximum algorithm (Widodo et
+	// al., Future Generation Computer Systems 2017). After the min size,
+	// the chunk is cut at the first byte greater or equal to the maximum
+	// byte of a fixed size window, with a single comparison per byte. It
+	// gives the lowest chunk size variance.
+	// https://doi.org/10.1016/j.future.2017.02.013
+	RAM
+)
+
+// rabin and buzhash are the algorithms of RabinChunker and
+// BuzhashChunker. They are out of the range of WithAlgorithm, which only
+// selects the algorithms of Chunker.
+const (
+	rabin Algorithm = 0x80 + iota
+	buzhash
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case FastCDC2016:
+		return "fastcdc2016"
+	case FastCDC2020:
+		return "fastcdc2020"
+	case AE:
+		return "ae"
+	case RAM:
+		return "ram"
+	case rabin:
+		return "rabin"
+	case buzhash:
+		return "buzhash"
+	default:
+		return "algorithm(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// search2020 is the cut point search of the FastCDC2020 algorithm. The
+// hash is the left-shifted gear hash, rolled two bytes per iteration: the
+// first byte is added with the table shifted one bit left, so the hash
+// itself is one bit ahead and is checked against the mask shifted one bit
+// left. It finds exactly the same cut points as rolling one byte at a
+// time.
+func (c *Chunker) search2020(window []byte) (uint, CutReason) {
+	length := uint(len(window))
+
+	normalSize := centerSize(c.avgSize, c.minSize, length)
+
+	var hash uint64
+	cut := c.minSize
+	maskS, maskSLS := c.maskS, c.maskS<<1
+	maskL, maskLLS := c.maskL, c.maskL<<1
+	gear, gearLS := c.table, c.tableLS
+
+	// Start by using the "harder" chunking judgement to find
+	// chunks that run smaller than the desired normal size.
+	if cut < normalSize {
+		src := window[cut:normalSize]
+		for ; len(src) >= 2; src = src[2:] {
+			hash = (hash << 2) + gearLS[src[0]]
+			if hash&maskSLS == 0 {
+				return normalSize - uint(len(src)) + 1, CutStrict
+			}
+			hash += gear[src[1]]
+			if hash&maskS == 0 {
+				return normalSize - uint(len(src)) + 2, CutStrict
+			}
+		}
+		if len(src) == 1 {
+			hash = (hash << 1) + gear[src[0]]
+			if hash&maskS == 0 {
+				return normalSize, CutStrict
+			}
+		}
+		cut = normalSize
+	}
+
+	// Fall back to using the "easier" chunking judgement to find chunks
+	// that run larger than the desired normal size but never bigger than
+	// the max size.
+	src := window[cut:]
+	for ; len(src) >= 2; src = src[2:] {
+		hash = (hash << 2) + gearLS[src[0]]
+		if hash&maskLLS == 0 {
+			return length - uint(len(src)) + 1, CutLoose
+		}
+		hash += gear[src[1]]
+		if hash&maskL == 0 {
+			return length - uint(len(src)) + 2, CutLoose
+		}
+	}
+	if len(src) == 1 {
+		hash = (hash << 1) + gear[src[0]]
+		if hash&maskL == 0 {
+			return length, CutLoose
+		}
+	}
+	return 0, CutNone
+}
+
+// spreadMask returns a mask with the given number of bits spread evenly
+// over the bits 16 to 47. With a left-shifted gear hash, the bit k only
+// depends on the last k+1 bytes, so the cut point depends on the last 48
+// bytes like in the 2020 FastCDC paper, and the mask shifted one bit left
+// does not overflow.
+func spreadMask(bits uint) uint64 {
+	if bits < 1 {
+		panic("bits too low")
+	}
+	if bits > 31 {
+		panic("bits too high")
+	}
+	var m uint64
+	for i := range bits {
+		m |= 1 << (47 - i*32/bits)
+	}
+	return m
+}
+
+// tableLS is the gear table shifted one bit left.
+var tableLS = shiftTable(&table)
+
+func shiftTable(t *[256]uint64) *[256]uint64 {
+	var ls [256]uint64
+	for i, v := range t {
+		ls[i] = v << 1
+	}
+	return &ls
+}
+
+// keyedTable derives a gear table from key with HKDF-SHA256.
+func keyedTable(key []byte) *[256]uint64 {
+	b, err := hkdf.Key(sha256.New, key, nil, "fastcdc gear table", 256*8)
+	if err != nil {
+		// HKDF-SHA256 can derive up to 8160 bytes.
+		panic(err)
+	}
+	var t [256]uint64
+	for i := range t {
+		t[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return &t
+}
+
+// validateTable rejects the degenerate gear tables. A table which maps
+// two bytes to the same value cannot tell them apart, and a bit which is
+// constant across the table is useless to the masks.
+func validateTable(t *[256]uint64) error {
+	seen := make(map[uint64]int, len(t))
+	for i, v := range t {
+		if j, ok := seen[v]; ok {
+			return fmt.Errorf("the bytes %d and %d have the same gear value: %w", j, i, ErrInvalidGearTable)
+		}
+		seen[v] = i
+	}
+
+	and, or := ^uint64(0), uint64(0)
+	for _, v := range t {
+		and &= v
+		or |= v
+	}
+	if constant := ^(and ^ or) & mask(31); constant != 0 {
+		return fmt.Errorf("the gear bits %#x are constant across the table: %w", constant, ErrInvalidGearTable)
+	}
+	return nil
+}
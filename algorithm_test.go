@@ -0,0 +1,340 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+var sekien2020Goldens = map[string]sekienGolden{
+	"16kChunks": {
+		Preset:  With16kChunks(),
+		MaxSize: 131_072,
+		Want: []chunkInfo{
+			{0, 18923},
+			{18923, 37442},
+			{56365, 13089},
+			{69454, 17881},
+			{87335, 11850},
+			{99185, 7623},
+			{106808, 2658},
+		},
+	},
+	"32kChunks": {
+		Preset:  With32kChunks(),
+		MaxSize: 262_144,
+		Want: []chunkInfo{
+			{0, 34084},
+			{34084, 28347},
+			{62431, 21027},
+			{83458, 26008},
+		},
+	},
+	"64kChunks": {
+		Preset:  With64kChunks(),
+		MaxSize: 524_288,
+		Want: []chunkInfo{
+			{0, 53699},
+			{53699, 17210},
+			{70909, 38557},
+		},
+	},
+}
+
+// TestSekien2020Chunks checks the FastCDC2020 golden chunks, whatever the
+// buffer size and the read pattern of the reader.
+func TestSekien2020Chunks(t *testing.T) {
+	data := sekienData(t)
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"full":       func(r io.Reader) io.Reader { return r },
+		"one byte":   iotest.OneByteReader,
+		"half":       iotest.HalfReader,
+		"7 bytes":    func(r io.Reader) io.Reader { return &chunkyReader{r, 7} },
+		"1000 bytes": func(r io.Reader) io.Reader { return &chunkyReader{r, 1000} },
+	}
+
+	for name, tc := range sekien2020Goldens {
+		t.Run(name, func(t *testing.T) {
+			for _, bufSize := range []uint{tc.MaxSize, 2*tc.MaxSize + 17} {
+				chunker, err := NewChunker(tc.Preset, WithAlgorithm(FastCDC2020), WithBufferSize(bufSize))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for readerName, wrap := range readers {
+					chunks := chunkAll(t, chunker, wrap(bytes.NewReader(data)), data)
+					if !slices.Equal(chunks, tc.Want) {
+						t.Errorf("%s reader, buffer size %d: chunks: want = %v, got = %v", readerName, bufSize, tc.Want, chunks)
+					}
+				}
+			}
+		})
+	}
+}
+
+// breakpoint2020Reference is a straightforward implementation of the
+// FastCDC2020 breakpoint, rolling one byte at a time.
+func breakpoint2020Reference(c *Chunker, window []byte) (uint, CutReason) {
+	length := uint(len(window))
+	if length <= c.minSize {
+		return 0, CutNone
+	}
+	if length > c.maxSize {
+		length = c.maxSize
+	}
+	normalSize := centerSize(c.avgSize, c.minSize, length)
+
+	var hash uint64
+	for cut := c.minSize; cut < length; {
+		hash = (hash << 1) + table[window[cut]]
+		cut++
+		m, reason := c.maskL, CutLoose
+		if cut <= normalSize {
+			m, reason = c.maskS, CutStrict
+		}
+		if hash&m == 0 {
+			return cut, reason
+		}
+	}
+	if length == c.maxSize {
+		return length, CutMaxSize
+	}
+	return 0, CutNone
+}
+
+// TestBreakpoint2020RollingTwoBytes checks that rolling two bytes per
+// iteration finds the same cut points as rolling one byte at a time.
+func TestBreakpoint2020RollingTwoBytes(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	configs := [][3]uint{
+		{64, 256, 1024},
+		{65, 256, 1024},
+		{100, 300, 1500},
+		{4096, 16_384, 131_072},
+		{200, 256, 1024},
+	}
+
+	for _, cfg := range configs {
+		chunker, err := NewChunker(WithChunksSize(cfg[0], cfg[1], cfg[2]), WithAlgorithm(FastCDC2020))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2000 {
+			window := randomData(rng.Uint64(), rng.IntN(int(cfg[2])*2))
+			want, wantReason := breakpoint2020Reference(chunker, window)
+			got, gotReason := chunker.breakpoint(window)
+			if got != want || gotReason != wantReason {
+				t.Fatalf("config %v, window size %d: want = %d (%s), got = %d (%s)", cfg, len(window), want, wantReason, got, gotReason)
+			}
+		}
+	}
+}
+
+func TestSpreadMask(t *testing.T) {
+	for n := uint(1); n <= 31; n++ {
+		m := spreadMask(n)
+		if got := uint(bits.OnesCount64(m)); got != n {
+			t.Errorf("bits: want = %d, got = %d", n, got)
+		}
+		if m&^0x0000_ffff_ffff_0000 != 0 {
+			t.Errorf("mask %#x out of bits 16 to 47", m)
+		}
+	}
+}
+
+func TestSpreadMaskPanic(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Bits     uint
+		PanicMsg string
+	}{
+		{"too low", 0, "bits too low"},
+		{"too high", 32, "bits too high"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.Name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("the code did not panic")
+				} else {
+					panicMsg := r.(string)
+					if panicMsg != tc.PanicMsg {
+						t.Errorf("want = %s, got = %s", tc.PanicMsg, r)
+					}
+				}
+			}()
+			spreadMask(tc.Bits)
+		})
+	}
+}
+
+func TestAlgorithmValidation(t *testing.T) {
+	_, err := NewChunker(WithAlgorithm(RAM + 1))
+	if !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Errorf("want = %s, got = %s", ErrInvalidAlgorithm, err)
+	}
+}
+
+func TestSekienKeyedChunks(t *testing.T) {
+	data := sekienData(t)
+	want := []chunkInfo{
+		{0, 32739},
+		{32739, 31072},
+		{63811, 17403},
+		{81214, 6683},
+		{87897, 10840},
+		{98737, 10729},
+	}
+
+	for _, frag := range []int{7, 1000, len(data)} {
+		chunker, err := NewChunker(With16kChunks(), WithKey([]byte("fastcdc")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks := chunkAll(t, chunker, &chunkyReader{bytes.NewReader(data), frag}, data)
+		if !slices.Equal(chunks, want) {
+			t.Errorf("%d bytes reads: chunks: want = %v, got = %v", frag, want, chunks)
+		}
+	}
+}
+
+// TestKeyedChunks checks that the chunks depend on the key, and that a
+// keyed chunker keeps the expected average chunk size.
+func TestKeyedChunks(t *testing.T) {
+	data := randomData(23, 16<<20)
+
+	for _, algo := range []Algorithm{FastCDC2016, FastCDC2020} {
+		t.Run(algo.String(), func(t *testing.T) {
+			chunkers := make(map[string]*Chunker)
+			for _, key := range []string{"", "foo", "bar"} {
+				opts := []Option{With16kChunks(), WithAlgorithm(algo)}
+				if key != "" {
+					opts = append(opts, WithKey([]byte(key)))
+				}
+				chunker, err := NewChunker(opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				chunkers[key] = chunker
+			}
+
+			results := make(map[string][]chunkInfo)
+			for key, chunker := range chunkers {
+				results[key] = chunkAll(t, chunker, bytes.NewReader(data), data)
+				avg := len(data) / len(results[key])
+				if avg < 14_000 || avg > 19_000 {
+					t.Errorf("key %q: average chunk size: want ~16384, got = %d", key, avg)
+				}
+			}
+			for _, pair := range [][2]string{{"", "foo"}, {"", "bar"}, {"foo", "bar"}} {
+				if slices.Equal(results[pair[0]], results[pair[1]]) {
+					t.Errorf("keys %q and %q produce the same chunks", pair[0], pair[1])
+				}
+			}
+
+			// The same key always produces the same chunks.
+			chunker, err := NewChunker(With16kChunks(), WithAlgorithm(algo), WithKey([]byte("foo")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkAll(t, chunker, bytes.NewReader(data), data); !slices.Equal(got, results["foo"]) {
+				t.Error("the same key must produce the same chunks")
+			}
+		})
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	for _, key := range [][]byte{nil, {}} {
+		_, err := NewChunker(WithKey(key))
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("want = %s, got = %s", ErrInvalidKey, err)
+		}
+	}
+}
+
+func TestGearTable(t *testing.T) {
+	data := sekienData(t)
+
+	t.Run("built-in table", func(t *testing.T) {
+		golden := sekienGoldens["16kChunks"]
+		gear := table
+		chunker, err := NewChunker(golden.Preset, WithGearTable(&gear))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The table is copied.
+		gear[0], gear[1] = gear[1], gear[0]
+
+		chunks := chunkAll(t, chunker, bytes.NewReader(data), data)
+		if !slices.Equal(chunks, golden.Want) {
+			t.Errorf("chunks: want = %v, got = %v", golden.Want, chunks)
+		}
+	})
+
+	t.Run("keyed table", func(t *testing.T) {
+		for _, algo := range []Algorithm{FastCDC2016, FastCDC2020} {
+			keyed, err := NewChunker(With16kChunks(), WithAlgorithm(algo), WithKey([]byte("foo")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			custom, err := NewChunker(With16kChunks(), WithAlgorithm(algo), WithGearTable(keyedTable([]byte("foo"))))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := chunkAll(t, keyed, bytes.NewReader(data), data)
+			got := chunkAll(t, custom, bytes.NewReader(data), data)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: chunks: want = %v, got = %v", algo, want, got)
+			}
+		}
+	})
+}
+
+func TestGearTableValidation(t *testing.T) {
+	duplicate := table
+	duplicate[200] = duplicate[100]
+
+	even := table
+	for i := range even {
+		even[i] <<= 1
+	}
+
+	narrow := table
+	for i := range narrow {
+		narrow[i] &= 0xffff
+	}
+
+	tests := map[string][]Option{
+		"nil table":         {WithGearTable(nil)},
+		"duplicate values":  {WithGearTable(&duplicate)},
+		"constant low bit":  {WithGearTable(&even)},
+		"constant high bit": {WithGearTable(&narrow)},
+		"table and key":     {WithGearTable(keyedTable([]byte("foo"))), WithKey([]byte("foo"))},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewChunker(opts...)
+			if !errors.Is(err, ErrInvalidGearTable) {
+				t.Errorf("want = %s, got = %s", ErrInvalidGearTable, err)
+			}
+		})
+	}
+
+	if err := validateTable(&table); err != nil {
+		t.Errorf("built-in table: want = nil, got = %s", err)
+	}
+}
@@ -53,3 +53,51 @@ func Benchmark32kChunks(b *testing.B) {
 func Benchmark64kChunks(b *testing.B) {
 	benchmark(b, randomData(155, 32*1024*1024), With64kChunks())
 }
+
+func Benchmark16kChunks2020(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With16kChunks(), WithAlgorithm(FastCDC2020))
+}
+
+func Benchmark32kChunks2020(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With32kChunks(), WithAlgorithm(FastCDC2020))
+}
+
+func Benchmark64kChunks2020(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With64kChunks(), WithAlgorithm(FastCDC2020))
+}
+
+func Benchmark16kChunksAE(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With16kChunks(), WithAlgorithm(AE))
+}
+
+func Benchmark16kChunksRAM(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With16kChunks(), WithAlgorithm(RAM))
+}
+
+func Benchmark64kChunksGearTable(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With64kChunks(), WithGearTable(keyedTable([]byte("bench"))))
+}
+
+func Benchmark64kChunksSHA256(b *testing.B) {
+	benchmark(b, randomData(155, 32*1024*1024), With64kChunks(), WithSHA256Digest())
+}
+
+func BenchmarkChunkReaderAt(b *testing.B) {
+	data := randomData(155, 256*1024*1024)
+	chunker, err := NewChunker(With64kChunks())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	reader := bytes.NewReader(data)
+	for b.Loop() {
+		for _, err := range chunker.ChunkReaderAt(reader, int64(len(data)), 0) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package fastcdc
+
+import (
+	"io"
+	"iter"
+)
+
+// Boundary is the position of a single chunk in the input stream.
+type Boundary struct {
+	// Offset is the position of the chunk in the input stream.
+	Offset int64
+	// Length is the size of the chunk.
+	Length int
+	// Sum is the digest of the chunk content when the chunker is
+	// configured with a digest, nil otherwise. It is only valid for the
+	// current iteration.
+	Sum []byte
+	// Reason tells why the chunk was cut.
+	Reason CutReason
+}
+
+// Boundaries returns an iterator that reads the stream and yields the
+// boundaries of its chunks in order, without exposing the chunk content.
+// It produces exactly the same boundaries as Chunks.
+//
+// When r implements io.Seeker and the chunker is not configured with a
+// digest, the cut point search never needs the first bytes of a chunk:
+// the first min size bytes with the FastCDC algorithms and RAM, all but
+// the last 7 bytes before the min size with AE, which reads 8-byte
+// values, and all but the last window size bytes before the min size
+// with RabinChunker and BuzhashChunker. So Boundaries seeks over them
+// instead of reading them, then reads forward by small steps until the
+// cut point, rather than a max size ahead. The stream then starts at the
+// current position of r and ends at its current end, as reported by
+// Seek. If seeking fails, for example on a pipe, r is read like any
+// other reader.
+//
+// Like Chunks, only one iteration must run at a time on a chunker.
+func (c *Chunker) Boundaries(r io.Reader) iter.Seq2[Boundary, error] {
+	return func(yield func(Boundary, error) bool) {
+		if rs, ok := r.(io.ReadSeeker); ok && c.newHash == nil {
+			if pos, err := rs.Seek(0, io.SeekCurrent); err == nil {
+				c.seekBoundaries(rs, pos, yield)
+				return
+			}
+		}
+
+		for chunk, err := range c.Chunks(r) {
+			if !yield(Boundary{Offset: chunk.Offset, Length: len(chunk.Data), Sum: chunk.Sum, Reason: chunk.Reason}, err) {
+				return
+			}
+		}
+	}
+}
+
+// seekBoundaries yields the boundaries of the stream starting at base,
+// reading only the bytes that the cut point search needs.
+//
+// The buffer always starts at the current chunk, and its first skip
+// bytes are a hole which is never read. The reader seeks over the hole,
+// then reads forward by steps until the cut point search finds a cut.
+// Every search restarts at the chunk start, which gives the same cut as a
+// search over the whole chunk: a search never looks past the cut it
+// returns.
+func (c *Chunker) seekBoundaries(rs io.ReadSeeker, base int64, yield func(Boundary, error) bool) {
+	if !c.busy.CompareAndSwap(false, true) {
+		panic("fastcdc: chunker already in use")
+	}
+	defer c.busy.Store(false)
+
+	last, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		yield(Boundary{}, err)
+		return
+	}
+	size := max(last-base, 0) // stream size
+	step := c.seekStep()
+
+	var (
+		offset int64 // stream position of the chunk, at buffer[0]
+		end    uint  // end of the buffered data
+		pos    = last
+	)
+	for offset < size {
+		var (
+			length uint
+			reason CutReason
+		)
+		for {
+			// Never read past the max size or the stream size, so the
+			// end of stream is the same as for Chunks.
+			limit := uint(min(int64(c.maxSize), size-offset))
+			if end >= limit {
+				length, reason = c.nextCut(c.buffer[:limit])
+				break
+			}
+			if end > c.minSize {
+				if length, reason = c.breakpoint(c.buffer[:end]); length != 0 {
+					break
+				}
+			}
+
+			end = max(end, min(c.skip, limit))
+			if pos != base+offset+int64(end) {
+				pos, err = rs.Seek(base+offset+int64(end), io.SeekStart)
+				if err != nil {
+					yield(Boundary{}, err)
+					return
+				}
+			}
+			want := min(end+step, limit)
+			for end < want {
+				n, err := rs.Read(c.buffer[end:want])
+				end += uint(n)
+				pos += int64(n)
+				if err == io.EOF {
+					// The stream shrank since its size was taken.
+					size = offset + int64(end)
+					break
+				}
+				if err != nil {
+					yield(Boundary{}, err)
+					return
+				}
+			}
+		}
+
+		if !yield(Boundary{Offset: offset, Length: int(length), Reason: reason}, nil) {
+			return
+		}
+		offset += int64(length)
+		// Keep the bytes read past the cut, unless they all fall in the
+		// hole of the next chunk.
+		if end-length > c.skip {
+			end = uint(copy(c.buffer, c.buffer[length:end]))
+		} else {
+			end = 0
+		}
+	}
+}
+
+// seekStep returns the number of bytes seekBoundaries reads between two
+// cut point searches. A chunk is read at most seekStep bytes past its cut,
+// and each step costs a search from the chunk start.
+func (c *Chunker) seekStep() uint {
+	return max(c.skip/4, 1024)
+}
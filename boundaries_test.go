@@ -0,0 +1,222 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// countingReadSeeker counts the bytes read from the underlying reader.
+type countingReadSeeker struct {
+	io.ReadSeeker
+	n int
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := c.ReadSeeker.Read(p)
+	c.n += n
+	return n, err
+}
+
+// unseekableReader is a ReadSeeker which fails to seek, like a pipe.
+type unseekableReader struct {
+	io.Reader
+}
+
+func (unseekableReader) Seek(int64, int) (int64, error) {
+	return 0, errors.New("illegal seek")
+}
+
+func boundariesAll(t *testing.T, chunker *Chunker, r io.Reader) []chunkInfo {
+	t.Helper()
+	var chunks []chunkInfo
+	for b, err := range chunker.Boundaries(r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunkInfo{b.Offset, b.Length})
+	}
+	return chunks
+}
+
+// checkBytesRead checks that Boundaries read n bytes of a seekable input
+// of the given chunks: it skips the first skip bytes of every chunk, and
+// reads at most seekStep bytes past every cut.
+func checkBytesRead(t *testing.T, c *Chunker, chunks []chunkInfo, n int) {
+	t.Helper()
+	step := int(c.seekStep())
+	var size, low, high int
+	for _, chunk := range chunks {
+		skipped := min(int(c.skip), chunk.Length)
+		size += chunk.Length
+		low += chunk.Length - skipped
+		high += chunk.Length - max(skipped-step, 0)
+	}
+	if n < low || n > high {
+		t.Errorf("bytes read: want within [%d, %d] of %d, got = %d", low, high, size, n)
+	}
+}
+
+func TestSekienBoundaries(t *testing.T) {
+	data := sekienData(t)
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"seeker":     func(r io.Reader) io.Reader { return r },
+		"unseekable": func(r io.Reader) io.Reader { return unseekableReader{r} },
+		"reader":     func(r io.Reader) io.Reader { return struct{ io.Reader }{r} },
+	}
+
+	for name, tc := range sekienGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for readerName, wrap := range readers {
+				chunks := boundariesAll(t, chunker, wrap(bytes.NewReader(data)))
+				if !slices.Equal(chunks, tc.Want) {
+					t.Errorf("%s: chunks: want = %v, got = %v", readerName, tc.Want, chunks)
+				}
+			}
+		})
+	}
+}
+
+// TestBoundariesRandomInput checks on random input that the boundaries of
+// a seekable input match the chunks, whatever the configuration, and
+// that the skipped bytes are not read.
+func TestBoundariesRandomInput(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	configs := [][3]uint{
+		{64, 256, 1024},
+		{4096, 16_384, 131_072},
+		{32_768, 65_536, 131_072},
+		{49_152, 65_536, 131_072},
+	}
+
+	for range 50 {
+		cfg := configs[rng.IntN(len(configs))]
+		bufSize := cfg[2] + uint(rng.IntN(1<<18))
+		chunker, err := NewChunker(WithChunksSize(cfg[0], cfg[1], cfg[2]), WithBufferSize(bufSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data := randomData(rng.Uint64(), rng.IntN(2<<20))
+		want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+		rs := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+		got := boundariesAll(t, chunker, rs)
+		if !slices.Equal(got, want) {
+			t.Fatalf("chunks: want = %v, got = %v, config = %v, input size = %d, buffer size = %d", want, got, cfg, len(data), bufSize)
+		}
+		checkBytesRead(t, chunker, want, rs.n)
+	}
+}
+
+// TestAlgorithmBoundaries checks that Boundaries, which seeks over the
+// bytes the cut point search never reads, and ChunkReaderAt find the same
+// chunks as Chunks, whatever the algorithm.
+func TestAlgorithmBoundaries(t *testing.T) {
+	data := randomData(2023, 3<<20)
+
+	cases := map[string]struct {
+		New  func(opts ...Option) (*Chunker, error)
+		Opts []Option
+	}{
+		"ae":      {withAlgorithm(AE), []Option{With16kChunks()}},
+		"ram":     {withAlgorithm(RAM), []Option{With16kChunks()}},
+		"rabin":   {newRabinChunker, []Option{With16kChunks()}},
+		"buzhash": {newBuzhashChunker, []Option{With16kChunks(), WithWindowSize(64)}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := tc.New(tc.Opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+			rs := &countingReadSeeker{ReadSeeker: bytes.NewReader(data)}
+			if got := boundariesAll(t, chunker, rs); !slices.Equal(got, want) {
+				t.Errorf("boundaries: want = %v, got = %v", want, got)
+			}
+			checkBytesRead(t, chunker, want, rs.n)
+			if got := chunkAllAt(t, chunker, data, 4, 256<<10); !slices.Equal(got, want) {
+				t.Errorf("chunks: want = %v, got = %v", want, got)
+			}
+		})
+	}
+}
+
+func TestBoundariesCurrentPosition(t *testing.T) {
+	data := randomData(17, 1<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chunkAll(t, chunker, bytes.NewReader(data[1000:]), data[1000:])
+
+	r := bytes.NewReader(data)
+	if _, err := r.Seek(1000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got := boundariesAll(t, chunker, r)
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks: want = %v, got = %v", want, got)
+	}
+}
+
+func TestBoundariesDigest(t *testing.T) {
+	data := randomData(19, 1<<20)
+
+	chunker, err := NewChunker(With16kChunks(), WithSHA256Digest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sums [][]byte
+	for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, bytes.Clone(chunk.Sum))
+	}
+
+	var i int
+	for b, err := range chunker.Boundaries(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Sum, sums[i]) {
+			t.Errorf("sum at offset %d: want = %x, got = %x", b.Offset, sums[i], b.Sum)
+		}
+		i++
+	}
+	if i != len(sums) {
+		t.Errorf("boundaries: want = %d, got = %d", len(sums), i)
+	}
+}
+
+func TestBoundariesEmptyInput(t *testing.T) {
+	chunker, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range chunker.Boundaries(bytes.NewReader(nil)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Error("no boundary expected on empty input")
+	}
+}
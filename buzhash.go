@@ -0,0 +1,96 @@
+package fastcdc
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+const defaultBuzhashWindowSize uint = 4095
+
+// BuzhashChunker splits a stream into content-defined chunks with a
+// buzhash (cyclic polynomial) over a rolling window. A cut point is
+// declared where the low bits of the hash are all zero. The table of the
+// hash is generated from a seed, so that chunkers with different seeds cut
+// at different points. The chunks are not compatible with any other
+// buzhash implementation.
+//
+// Like RabinChunker, it embeds Chunker and shares its invariants, and the
+// expected chunk size is min plus the nearest power of two of avg - min.
+type BuzhashChunker struct {
+	*Chunker
+}
+
+// NewBuzhashChunker returns a buzhash chunker. It accepts the chunk size,
+// buffer size and digest options of NewChunker, along with WithSeed and
+// WithWindowSize. The options specific to the other algorithms, like
+// WithAlgorithm, WithNormalization, WithKey, WithGearTable or
+// WithPolynomial, are rejected.
+func NewBuzhashChunker(opts ...Option) (*BuzhashChunker, error) {
+	c, err := newRollingChunker(buzhash, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BuzhashChunker{c}, nil
+}
+
+// initBuzhash sets up the seeded table and the mask of the buzhash.
+func (c *Chunker) initBuzhash(config *config) error {
+	if config.key != nil || config.table != nil {
+		return fmt.Errorf("the buzhash chunker derives its table from the seed: %w", ErrInvalidGearTable)
+	}
+	if config.polynomial != 0 {
+		return fmt.Errorf("the polynomial only applies to the Rabin chunker: %w", ErrInvalidAlgorithm)
+	}
+
+	// The default window shrinks to the min size, which is the largest
+	// window allowed.
+	if err := c.initRollingWindow(config, min(defaultBuzhashWindowSize, config.minSize)); err != nil {
+		return err
+	}
+
+	var seed uint32
+	if config.seed != nil {
+		seed = *config.seed
+	}
+	c.table = buzhashTable(seed)
+	return nil
+}
+
+// buzhashTable returns the table of the buzhash: 256 random 32-bit
+// values, generated with SplitMix64 from the seed.
+func buzhashTable(seed uint32) *[256]uint64 {
+	var t [256]uint64
+	state := uint64(seed)
+	for i := range t {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		z ^= z >> 31
+		t[i] = z >> 32
+	}
+	return &t
+}
+
+// searchBuzhash is the cut point search of BuzhashChunker. The hash only
+// depends on the window size bytes before a cut point, so the search
+// starts window size bytes before the min size.
+func (c *Chunker) searchBuzhash(window []byte) (uint, CutReason) {
+	t := c.table
+	mask := uint32(c.maskS)
+	// The byte leaving the window has been rotated window size times.
+	out := int(c.window % 32)
+
+	var hash uint32
+	for _, b := range window[c.skip:c.minSize] {
+		hash = bits.RotateLeft32(hash, 1) ^ uint32(t[b])
+	}
+
+	for cut := c.minSize; cut < uint(len(window)); cut++ {
+		hash = bits.RotateLeft32(hash, 1) ^ bits.RotateLeft32(uint32(t[window[cut-c.window]]), out) ^ uint32(t[window[cut]])
+		if hash&mask == 0 {
+			return cut + 1, CutContent
+		}
+	}
+	return 0, CutNone
+}
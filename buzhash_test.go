@@ -0,0 +1,171 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"slices"
+	"testing"
+)
+
+var sekienBuzhashGoldens = map[string]sekienGolden{
+	"16kChunks": {
+		Preset:  With16kChunks(),
+		MaxSize: 131_072,
+		Want: []chunkInfo{
+			{0, 21859}, {21859, 23062}, {44921, 4341}, {49262, 12770},
+			{62032, 37127}, {99159, 10307},
+		},
+	},
+	"8k16k32kChunks": {
+		Preset:  WithChunksSize(8192, 16_384, 32_768),
+		MaxSize: 32_768,
+		Want: []chunkInfo{
+			{0, 21859}, {21859, 23062}, {44921, 8367}, {53288, 8744},
+			{62032, 23578}, {85610, 13549}, {99159, 10307},
+		},
+	},
+}
+
+// newBuzhashChunker returns the Chunker of a BuzhashChunker.
+func newBuzhashChunker(opts ...Option) (*Chunker, error) {
+	c, err := NewBuzhashChunker(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Chunker, nil
+}
+
+func TestBuzhashChunkerValidation(t *testing.T) {
+	cases := map[string]struct {
+		Opts []Option
+		Err  error
+	}{
+		"default":             {nil, nil},
+		"seed":                {[]Option{WithSeed(42)}, nil},
+		"custom window":       {[]Option{WithWindowSize(48)}, nil},
+		"window of min size":  {[]Option{WithChunksSize(64, 256, 1024), WithWindowSize(64)}, nil},
+		"default window":      {[]Option{WithChunksSize(1024, 4096, 16_384)}, nil},
+		"window over min":     {[]Option{WithChunksSize(64, 256, 1024), WithWindowSize(65)}, ErrInvalidWindowSize},
+		"invalid chunk size":  {[]Option{WithChunksSize(32, 256, 1024)}, ErrInvalidChunkSize},
+		"invalid buffer size": {[]Option{WithBufferSize(1024)}, ErrInvalidBufferSize},
+		"algorithm":           {[]Option{WithAlgorithm(FastCDC2020)}, ErrInvalidAlgorithm},
+		"normalization":       {[]Option{WithNormalization(2)}, ErrInvalidNormalization},
+		"key":                 {[]Option{WithKey([]byte("secret"))}, ErrInvalidGearTable},
+		"gear table":          {[]Option{WithGearTable(&table)}, ErrInvalidGearTable},
+		"polynomial":          {[]Option{WithPolynomial(defaultPolynomial)}, ErrInvalidAlgorithm},
+		"ronomon":             {[]Option{WithRonomonCompat()}, ErrInvalidAlgorithm},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewBuzhashChunker(tc.Opts...)
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("error: want = %v, got = %v", tc.Err, err)
+			}
+		})
+	}
+
+	if _, err := NewChunker(WithSeed(42)); !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Errorf("error: want = %v, got = %v", ErrInvalidAlgorithm, err)
+	}
+	if _, err := NewRabinChunker(WithSeed(42)); !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Errorf("error: want = %v, got = %v", ErrInvalidAlgorithm, err)
+	}
+}
+
+// TestBuzhashDefaultWindow checks that the default window shrinks to a
+// min size under 4095.
+func TestBuzhashDefaultWindow(t *testing.T) {
+	data := randomData(2048, 1<<20)
+	chunker, err := NewBuzhashChunker(WithChunksSize(2048, 8192, 65_536))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunker.window != 2048 {
+		t.Errorf("window: want = 2048, got = %d", chunker.window)
+	}
+
+	explicit, err := NewBuzhashChunker(WithChunksSize(2048, 8192, 65_536), WithWindowSize(2048))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chunkAll(t, explicit.Chunker, bytes.NewReader(data), data)
+	if got := chunkAll(t, chunker.Chunker, bytes.NewReader(data), data); !slices.Equal(got, want) {
+		t.Errorf("chunks: want = %v, got = %v", want, got)
+	}
+}
+
+func TestSekienBuzhashChunks(t *testing.T) {
+	data := sekienData(t)
+	for name, tc := range sekienBuzhashGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewBuzhashChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks := chunkAll(t, chunker.Chunker, bytes.NewReader(data), data)
+			if !slices.Equal(chunks, tc.Want) {
+				t.Errorf("chunks: want = %v, got = %v", tc.Want, chunks)
+			}
+		})
+	}
+}
+
+// TestBuzhashRolling checks every content cut point against the buzhash of
+// the window before it, computed from scratch.
+func TestBuzhashRolling(t *testing.T) {
+	data := randomData(24, 4<<20)
+	for _, window := range []uint{31, 64, 4095} {
+		chunker, err := NewBuzhashChunker(With16kChunks(), WithWindowSize(window), WithSeed(0xdeadbeef))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, mask := int(window), uint32(chunker.maskS)
+
+		var content int
+		for chunk := range chunker.ChunkBytes(data) {
+			n := len(chunk.Data)
+			if chunk.Reason == CutEOF {
+				continue
+			}
+			if n < 4096 || n > 131_072 {
+				t.Fatalf("chunk size out of bounds: %d", n)
+			}
+			if chunk.Reason != CutContent {
+				continue
+			}
+			content++
+			var hash uint32
+			for i, b := range chunk.Data[n-w:] {
+				hash ^= bits.RotateLeft32(uint32(chunker.table[b]), w-1-i)
+			}
+			if hash&mask != 0 {
+				t.Fatalf("hash at offset %d: want = %#x masked to 0, got = %#x", chunk.Offset+int64(n), hash, hash&mask)
+			}
+		}
+		if content == 0 {
+			t.Errorf("window %d: no content cut point", window)
+		}
+	}
+}
+
+func TestBuzhashSeed(t *testing.T) {
+	data := randomData(2024, 1<<20)
+	var previous []chunkInfo
+	fingerprints := make(map[uint64]uint32)
+	for _, seed := range []uint32{0, 1, 0xdeadbeef} {
+		chunker, err := NewBuzhashChunker(With16kChunks(), WithSeed(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks := chunkAll(t, chunker.Chunker, bytes.NewReader(data), data)
+		if slices.Equal(chunks, previous) {
+			t.Errorf("seed %#x: same chunks as the previous seed", seed)
+		}
+		previous = chunks
+		if other, ok := fingerprints[chunker.Fingerprint()]; ok {
+			t.Errorf("fingerprint: seeds %#x and %#x share %#x", seed, other, chunker.Fingerprint())
+		}
+		fingerprints[chunker.Fingerprint()] = seed
+	}
+}
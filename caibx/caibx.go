@@ -0,0 +1,196 @@
+// Package caibx implements the casync blob index format (.caibx), so that the streams chunked by a fastcdc.Chunker can
+// be served to casync and desync clients.
+//
+// An index is made of a FORMAT_INDEX header with the feature flags and the chunk sizes, followed by a FORMAT_TABLE of
+// the chunks. Each table item holds the end offset of a chunk and its SHA-256 digest, and the table ends with a tail
+// pointing back to its start. All the integers are little-endian.
+package caibx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+const (
+	typeIndex      = 0x96824d9c7b129ff9
+	typeTable      = 0xe75b9e112f17417d
+	tableTailMark  = 0x4b4f050e5549ecd1
+	indexSize      = 48
+	tableHeadSize  = 16
+	tableItemSize  = 40
+	tableTailSize  = 40
+	tableSizeUnset = ^uint64(0)
+)
+
+// Feature flags of the FORMAT_INDEX header.
+const (
+	// FlagSHA512_256 tells that the chunks are addressed by their
+	// SHA-512/256 digest instead of their SHA-256 digest. It is not
+	// supported by this package.
+	FlagSHA512_256 uint64 = 0x2000000000000000
+	// FlagExcludeNoDump is set by casync and desync on the blob indexes.
+	FlagExcludeNoDump uint64 = 0x8000000000000000
+)
+
+var (
+	ErrInvalidIndex = errors.New("invalid caibx index")
+	ErrUnsupported  = errors.New("unsupported caibx index")
+)
+
+// Index is a casync blob index.
+type Index struct {
+	// FeatureFlags are the casync feature flags.
+	FeatureFlags uint64
+	// MinSize, AvgSize and MaxSize are the chunk sizes of the chunker.
+	MinSize uint64
+	AvgSize uint64
+	MaxSize uint64
+	// Chunks are the chunk references of the stream, in order.
+	Chunks []store.ChunkRef
+}
+
+// New returns the index of a stream chunked with c, for example by
+// store.StoreStream.
+func New(c *fastcdc.Chunker, refs []store.ChunkRef) *Index {
+	minSize, avgSize, maxSize := c.Sizes()
+	return &Index{
+		FeatureFlags: FlagExcludeNoDump,
+		MinSize:      uint64(minSize),
+		AvgSize:      uint64(avgSize),
+		MaxSize:      uint64(maxSize),
+		Chunks:       refs,
+	}
+}
+
+// WriteTo encodes the index to w. The chunk references must be
+// contiguous, starting at offset 0. It implements io.WriterTo.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	if idx.FeatureFlags&FlagSHA512_256 != 0 {
+		return 0, fmt.Errorf("SHA-512/256 digests: %w", ErrUnsupported)
+	}
+
+	var offset int64
+	for _, ref := range idx.Chunks {
+		if ref.Offset != offset || ref.Length <= 0 {
+			return 0, fmt.Errorf("chunk at offset %d of %d bytes, want offset %d: %w", ref.Offset, ref.Length, offset, ErrInvalidIndex)
+		}
+		offset += int64(ref.Length)
+	}
+
+	// Count the bytes accepted by w, not the ones buffered by bw.
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	b := make([]byte, 0, indexSize+tableHeadSize)
+	b = binary.LittleEndian.AppendUint64(b, indexSize)
+	b = binary.LittleEndian.AppendUint64(b, typeIndex)
+	b = binary.LittleEndian.AppendUint64(b, idx.FeatureFlags)
+	b = binary.LittleEndian.AppendUint64(b, idx.MinSize)
+	b = binary.LittleEndian.AppendUint64(b, idx.AvgSize)
+	b = binary.LittleEndian.AppendUint64(b, idx.MaxSize)
+	b = binary.LittleEndian.AppendUint64(b, tableSizeUnset)
+	b = binary.LittleEndian.AppendUint64(b, typeTable)
+	if _, err := bw.Write(b); err != nil {
+		return cw.n, err
+	}
+
+	for _, ref := range idx.Chunks {
+		b = binary.LittleEndian.AppendUint64(b[:0], uint64(ref.Offset)+uint64(ref.Length))
+		b = append(b, ref.Digest[:]...)
+		if _, err := bw.Write(b); err != nil {
+			return cw.n, err
+		}
+	}
+
+	b = binary.LittleEndian.AppendUint64(b[:0], 0)
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, indexSize)
+	b = binary.LittleEndian.AppendUint64(b, uint64(tableHeadSize+len(idx.Chunks)*tableItemSize+tableTailSize))
+	b = binary.LittleEndian.AppendUint64(b, tableTailMark)
+	if _, err := bw.Write(b); err != nil {
+		return cw.n, err
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Decode reads a casync blob index from r. It returns ErrInvalidIndex if
+// the index is malformed, and ErrUnsupported if its chunks are not
+// addressed by their SHA-256 digest.
+func Decode(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+
+	var head [indexSize + tableHeadSize]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		return nil, truncated(err)
+	}
+	if binary.LittleEndian.Uint64(head[0:]) != indexSize || binary.LittleEndian.Uint64(head[8:]) != typeIndex {
+		return nil, fmt.Errorf("not a FORMAT_INDEX header: %w", ErrInvalidIndex)
+	}
+	idx := &Index{
+		FeatureFlags: binary.LittleEndian.Uint64(head[16:]),
+		MinSize:      binary.LittleEndian.Uint64(head[24:]),
+		AvgSize:      binary.LittleEndian.Uint64(head[32:]),
+		MaxSize:      binary.LittleEndian.Uint64(head[40:]),
+	}
+	if idx.FeatureFlags&FlagSHA512_256 != 0 {
+		return nil, fmt.Errorf("SHA-512/256 digests: %w", ErrUnsupported)
+	}
+	if idx.MinSize == 0 || idx.MinSize > idx.AvgSize || idx.AvgSize > idx.MaxSize {
+		return nil, fmt.Errorf("invalid chunk sizes %d/%d/%d: %w", idx.MinSize, idx.AvgSize, idx.MaxSize, ErrInvalidIndex)
+	}
+	if binary.LittleEndian.Uint64(head[48:]) != tableSizeUnset || binary.LittleEndian.Uint64(head[56:]) != typeTable {
+		return nil, fmt.Errorf("not a FORMAT_TABLE header: %w", ErrInvalidIndex)
+	}
+
+	var item [tableItemSize]byte
+	var offset uint64
+	for {
+		if _, err := io.ReadFull(br, item[:]); err != nil {
+			return nil, truncated(err)
+		}
+		end := binary.LittleEndian.Uint64(item[0:])
+		if end == 0 && binary.LittleEndian.Uint64(item[8:]) == 0 {
+			// The zero fill of the table tail.
+			break
+		}
+		if end <= offset || end-offset > idx.MaxSize {
+			return nil, fmt.Errorf("chunk of %d bytes at offset %d: %w", end-offset, offset, ErrInvalidIndex)
+		}
+		ref := store.ChunkRef{Offset: int64(offset), Length: int(end - offset)}
+		copy(ref.Digest[:], item[8:])
+		idx.Chunks = append(idx.Chunks, ref)
+		offset = end
+	}
+
+	tableSize := uint64(tableHeadSize + len(idx.Chunks)*tableItemSize + tableTailSize)
+	if binary.LittleEndian.Uint64(item[16:]) != indexSize ||
+		binary.LittleEndian.Uint64(item[24:]) != tableSize ||
+		binary.LittleEndian.Uint64(item[32:]) != tableTailMark {
+		return nil, fmt.Errorf("invalid table tail: %w", ErrInvalidIndex)
+	}
+	return idx, nil
+}
+
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", ErrInvalidIndex, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,149 @@
+package caibx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+func sekienData(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../fixtures/SekienAkashita.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func sekienIndex(t *testing.T) (*Index, []byte) {
+	t.Helper()
+	data := sekienData(t)
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := store.StoreStream(chunker, bytes.NewReader(data), store.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := New(chunker, refs)
+
+	var buf bytes.Buffer
+	n, err := idx.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("written: want = %d, got = %d", buf.Len(), n)
+	}
+	return idx, buf.Bytes()
+}
+
+func TestSekienRoundTrip(t *testing.T) {
+	idx, b := sekienIndex(t)
+
+	if want := indexSize + tableHeadSize + len(idx.Chunks)*tableItemSize + tableTailSize; len(b) != want {
+		t.Errorf("size: want = %d, got = %d", want, len(b))
+	}
+	header := []uint64{indexSize, typeIndex, FlagExcludeNoDump, 4096, 16384, 131072, tableSizeUnset, typeTable}
+	for i, want := range header {
+		if got := binary.LittleEndian.Uint64(b[i*8:]); got != want {
+			t.Errorf("header word %d: want = %#x, got = %#x", i, want, got)
+		}
+	}
+	// The first item holds the end offset of the first chunk.
+	if got := binary.LittleEndian.Uint64(b[64:]); got != 22366 {
+		t.Errorf("first chunk end: want = 22366, got = %d", got)
+	}
+
+	got, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.FeatureFlags != idx.FeatureFlags || got.MinSize != idx.MinSize || got.AvgSize != idx.AvgSize || got.MaxSize != idx.MaxSize {
+		t.Errorf("header: want = %v, got = %v", idx, got)
+	}
+	if !slices.Equal(got.Chunks, idx.Chunks) {
+		t.Errorf("chunks: want = %v, got = %v", idx.Chunks, got.Chunks)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, valid := sekienIndex(t)
+
+	patch := func(at int, v uint64) []byte {
+		b := slices.Clone(valid)
+		binary.LittleEndian.PutUint64(b[at:], v)
+		return b
+	}
+	tail := len(valid) - tableTailSize
+
+	tests := map[string]struct {
+		Data []byte
+		Err  error
+	}{
+		"empty":           {nil, ErrInvalidIndex},
+		"truncated":       {valid[:len(valid)-1], ErrInvalidIndex},
+		"bad type":        {patch(8, typeTable), ErrInvalidIndex},
+		"bad sizes":       {patch(24, 1<<20), ErrInvalidIndex},
+		"bad table":       {patch(56, typeIndex), ErrInvalidIndex},
+		"oversized chunk": {patch(64, 1<<20), ErrInvalidIndex},
+		"bad tail size":   {patch(tail+24, 0), ErrInvalidIndex},
+		"bad tail marker": {patch(tail+32, 0), ErrInvalidIndex},
+		"SHA-512/256":     {patch(16, FlagExcludeNoDump|FlagSHA512_256), ErrUnsupported},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tc.Data)); !errors.Is(err, tc.Err) {
+				t.Errorf("want = %s, got = %s", tc.Err, err)
+			}
+		})
+	}
+}
+
+func TestWriteToInvalid(t *testing.T) {
+	idx := &Index{MinSize: 1, AvgSize: 2, MaxSize: 4, Chunks: []store.ChunkRef{{Offset: 1, Length: 2}}}
+	if _, err := idx.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("want = %s, got = %s", ErrInvalidIndex, err)
+	}
+}
+
+// shortWriter accepts at most n bytes, then fails.
+type shortWriter struct {
+	n int
+}
+
+var errShortWrite = errors.New("short write")
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errShortWrite
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+// TestWriteToShortWrite checks that WriteTo reports the bytes accepted
+// by the writer, not the ones buffered before the failure.
+func TestWriteToShortWrite(t *testing.T) {
+	idx, b := sekienIndex(t)
+
+	for _, limit := range []int{0, 100, len(b) - 1} {
+		n, err := idx.WriteTo(&shortWriter{limit})
+		if !errors.Is(err, errShortWrite) {
+			t.Errorf("limit %d: error: want = %v, got = %v", limit, errShortWrite, err)
+		}
+		if n != int64(limit) {
+			t.Errorf("limit %d: written: want = %d, got = %d", limit, limit, n)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fastcdc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"iter"
+)
+
+const checkpointVersion = 1
+
+// checkpointSize is the size of a serialized checkpoint: the version,
+// the offset and the configuration fingerprint.
+const checkpointSize = 1 + 8 + 8
+
+// Checkpoint is the state of a chunking right after a chunk boundary. A
+// cut point only depends on the bytes after the previous boundary, so
+// resuming the chunking from a checkpoint yields exactly the same
+// remaining chunks as an uninterrupted run.
+type Checkpoint struct {
+	// Offset is the stream position of the next chunk.
+	Offset int64
+	// fingerprint identifies the configuration of the chunker.
+	fingerprint uint64
+}
+
+// Checkpoint returns the checkpoint right after the given chunk, yielded
+// by this chunker.
+func (c *Chunker) Checkpoint(chunk Chunk) Checkpoint {
+	return Checkpoint{Offset: chunk.Offset + int64(len(chunk.Data)), fingerprint: c.fingerprint}
+}
+
+// Resume returns an iterator that resumes the chunking from cp, like
+// Chunks. The reader must deliver the stream from the checkpoint offset,
+// for example a file seeked at Checkpoint.Offset, and the chunk offsets
+// continue from there. If cp was taken with a chunker which produces
+// different boundaries, the iterator yields a zero Chunk with
+// ErrCheckpointMismatch and stops.
+func (c *Chunker) Resume(cp Checkpoint, r io.Reader) iter.Seq2[Chunk, error] {
+	if cp.fingerprint != c.fingerprint {
+		return func(yield func(Chunk, error) bool) {
+			yield(Chunk{}, ErrCheckpointMismatch)
+		}
+	}
+	return c.chunks(context.Background(), r, cp.Offset)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (cp Checkpoint) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, checkpointSize)
+	b = append(b, checkpointVersion)
+	b = binary.BigEndian.AppendUint64(b, uint64(cp.Offset))
+	b = binary.BigEndian.AppendUint64(b, cp.fingerprint)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (cp *Checkpoint) UnmarshalBinary(data []byte) error {
+	if len(data) != checkpointSize {
+		return fmt.Errorf("the checkpoint must be %d bytes long: %w", checkpointSize, ErrInvalidCheckpoint)
+	}
+	if data[0] != checkpointVersion {
+		return fmt.Errorf("unsupported checkpoint version %d: %w", data[0], ErrInvalidCheckpoint)
+	}
+	offset := int64(binary.BigEndian.Uint64(data[1:]))
+	if offset < 0 {
+		return fmt.Errorf("negative checkpoint offset: %w", ErrInvalidCheckpoint)
+	}
+	cp.Offset = offset
+	cp.fingerprint = binary.BigEndian.Uint64(data[9:])
+	return nil
+}
+
+// configFingerprint returns a hash of everything that determines the
+// chunk boundaries. The buffer size and the digest do not.
+func (c *Chunker) configFingerprint() uint64 {
+	h := fnv.New64a()
+	var b []byte
+	b = append(b, byte(c.algorithm))
+	b = binary.BigEndian.AppendUint64(b, uint64(c.minSize))
+	b = binary.BigEndian.AppendUint64(b, uint64(c.avgSize))
+	b = binary.BigEndian.AppendUint64(b, uint64(c.maxSize))
+	b = binary.BigEndian.AppendUint64(b, c.maskS)
+	b = binary.BigEndian.AppendUint64(b, c.maskL)
+	if c.table != nil {
+		for _, v := range c.table {
+			b = binary.BigEndian.AppendUint64(b, v)
+		}
+	}
+	if c.rabin != nil {
+		b = binary.BigEndian.AppendUint64(b, c.rabin.pol)
+	}
+	if c.window != 0 {
+		b = binary.BigEndian.AppendUint64(b, uint64(c.window))
+	}
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
@@ -0,0 +1,186 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestResume checks that resuming from the checkpoint taken after any
+// chunk yields the same remaining chunks as an uninterrupted run.
+func TestResume(t *testing.T) {
+	data := randomData(31, 2<<20)
+
+	for _, algo := range []Algorithm{FastCDC2016, FastCDC2020} {
+		t.Run(algo.String(), func(t *testing.T) {
+			chunker, err := NewChunker(With16kChunks(), WithAlgorithm(algo))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var checkpoints [][]byte
+			for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := chunker.Checkpoint(chunk).MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkpoints = append(checkpoints, b)
+			}
+			want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+			// A new chunker with the same configuration, but another buffer
+			// size, resumes the chunking.
+			resumed, err := NewChunker(With16kChunks(), WithAlgorithm(algo), WithBufferSize(200_000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, b := range checkpoints {
+				var cp Checkpoint
+				if err := cp.UnmarshalBinary(b); err != nil {
+					t.Fatal(err)
+				}
+
+				var got []chunkInfo
+				for chunk, err := range resumed.Resume(cp, &chunkyReader{bytes.NewReader(data[cp.Offset:]), 1000}) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(chunk.Data, data[chunk.Offset:chunk.Offset+int64(len(chunk.Data))]) {
+						t.Fatalf("chunk content mismatch at offset %d", chunk.Offset)
+					}
+					got = append(got, chunkInfo{chunk.Offset, len(chunk.Data)})
+				}
+				if !slices.Equal(got, want[i+1:]) {
+					t.Fatalf("resume after chunk %d: want = %v, got = %v", i, want[i+1:], got)
+				}
+			}
+		})
+	}
+}
+
+func TestResumeMismatch(t *testing.T) {
+	data := sekienData(t)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp Checkpoint
+	for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		cp = chunker.Checkpoint(chunk)
+		break
+	}
+
+	tests := map[string][]Option{
+		"chunk size":    {With32kChunks()},
+		"algorithm":     {With16kChunks(), WithAlgorithm(FastCDC2020)},
+		"normalization": {With16kChunks(), WithNormalization(2)},
+		"key":           {With16kChunks(), WithKey([]byte("foo"))},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			other, err := NewChunker(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotErr error
+			for _, err := range other.Resume(cp, bytes.NewReader(data[cp.Offset:])) {
+				gotErr = err
+			}
+			if !errors.Is(gotErr, ErrCheckpointMismatch) {
+				t.Errorf("want = %s, got = %s", ErrCheckpointMismatch, gotErr)
+			}
+		})
+	}
+
+	t.Run("zero checkpoint", func(t *testing.T) {
+		var gotErr error
+		for _, err := range chunker.Resume(Checkpoint{}, bytes.NewReader(data)) {
+			gotErr = err
+		}
+		if !errors.Is(gotErr, ErrCheckpointMismatch) {
+			t.Errorf("want = %s, got = %s", ErrCheckpointMismatch, gotErr)
+		}
+	})
+}
+
+func TestCheckpointUnmarshal(t *testing.T) {
+	valid, err := Checkpoint{Offset: 42, fingerprint: 7}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cp Checkpoint
+	if err := cp.UnmarshalBinary(valid); err != nil {
+		t.Fatal(err)
+	}
+	if cp.Offset != 42 || cp.fingerprint != 7 {
+		t.Errorf("want = {42 7}, got = %v", cp)
+	}
+
+	version := slices.Clone(valid)
+	version[0] = 2
+	negative := slices.Clone(valid)
+	negative[1] = 0x80
+
+	tests := map[string][]byte{
+		"empty":           nil,
+		"short":           valid[:10],
+		"long":            append(slices.Clone(valid), 0),
+		"unknown version": version,
+		"negative offset": negative,
+	}
+
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cp Checkpoint
+			if err := cp.UnmarshalBinary(b); !errors.Is(err, ErrInvalidCheckpoint) {
+				t.Errorf("want = %s, got = %s", ErrInvalidCheckpoint, err)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	configs := map[string][]Option{
+		"default":       nil,
+		"16k":           {With16kChunks()},
+		"algorithm":     {WithAlgorithm(FastCDC2020)},
+		"normalization": {WithNormalization(2)},
+		"key":           {WithKey([]byte("foo"))},
+		"other key":     {WithKey([]byte("bar"))},
+	}
+
+	seen := make(map[uint64]string)
+	for name, opts := range configs {
+		chunker, err := NewChunker(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[chunker.Fingerprint()]; ok {
+			t.Errorf("%s and %s have the same fingerprint", name, other)
+		}
+		seen[chunker.Fingerprint()] = name
+	}
+
+	// The buffer size and the digest do not change the chunks.
+	a, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewChunker(WithBufferSize(1<<21), WithSHA256Digest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("buffer size and digest must not change the fingerprint")
+	}
+}
@@ -1,11 +1,14 @@
 package fastcdc
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"iter"
 	"math"
+	"strconv"
 	"sync/atomic"
 )
 
@@ -21,8 +24,18 @@ const (
 )
 
 var (
-	ErrInvalidChunkSize  = errors.New("invalid chunk size")
-	ErrInvalidBufferSize = errors.New("invalid buffer size")
+	ErrInvalidChunkSize     = errors.New("invalid chunk size")
+	ErrInvalidBufferSize    = errors.New("invalid buffer size")
+	ErrInvalidAlgorithm     = errors.New("invalid algorithm")
+	ErrInvalidKey           = errors.New("invalid key")
+	ErrInvalidGearTable     = errors.New("invalid gear table")
+	ErrInvalidCheckpoint    = errors.New("invalid checkpoint")
+	ErrCheckpointMismatch   = errors.New("checkpoint of another chunker configuration")
+	ErrInvalidNormalization = errors.New("invalid normalization level")
+	ErrWriterClosed         = errors.New("write on closed writer")
+	ErrEmptySample          = errors.New("empty sample")
+	ErrInvalidPolynomial    = errors.New("invalid polynomial")
+	ErrInvalidWindowSize    = errors.New("invalid window size")
 )
 
 // Chunk is a single chunk of the input stream.
@@ -33,19 +46,79 @@ type Chunk struct {
 	Data []byte
 	// Offset is the position of the chunk in the input stream.
 	Offset int64
+	// Sum is the digest of the chunk content when the chunker is
+	// configured with a digest, nil otherwise. Like Data, it is only
+	// valid for the current iteration.
+	Sum []byte
+	// Reason tells why the chunk was cut.
+	Reason CutReason
+}
+
+// CutReason tells why a chunk was cut.
+type CutReason uint8
+
+const (
+	// CutNone is the reason of the zero Chunk yielded along with an error.
+	CutNone CutReason = iota
+	// CutStrict is a cut point found with the strict mask, before the
+	// normal size.
+	CutStrict
+	// CutLoose is a cut point found with the loose mask, after the normal
+	// size.
+	CutLoose
+	// CutMaxSize is a chunk forced to the max size, without cut point.
+	CutMaxSize
+	// CutEOF is the last chunk of the stream, without cut point.
+	CutEOF
+	// CutContent is a cut point found by an algorithm without normalized
+	// chunking: RabinChunker, BuzhashChunker, AE or RAM.
+	CutContent
+)
+
+func (r CutReason) String() string {
+	switch r {
+	case CutNone:
+		return "none"
+	case CutStrict:
+		return "strict"
+	case CutLoose:
+		return "loose"
+	case CutMaxSize:
+		return "max size"
+	case CutEOF:
+		return "eof"
+	case CutContent:
+		return "content"
+	default:
+		return "reason(" + strconv.Itoa(int(r)) + ")"
+	}
 }
 
 // Chunker splits a stream into content-defined chunks. Identical input
 // with an identical chunk size configuration always produces identical
 // chunks, whatever the buffer size or the read pattern of the reader.
 type Chunker struct {
-	buffer  []byte
-	minSize uint
-	avgSize uint
-	maxSize uint
-	maskS   uint64
-	maskL   uint64
-	busy    atomic.Bool
+	buffer    []byte
+	algorithm Algorithm
+	minSize   uint
+	avgSize   uint
+	maxSize   uint
+	maskS     uint64
+	maskL     uint64
+	table     *[256]uint64 // gear table
+	tableLS   *[256]uint64 // gear table shifted one bit left
+	rabin     *rabinTables // tables of the Rabin fingerprint
+	window    uint         // rolling window size of the Rabin and buzhash chunkers
+	// skip is the number of bytes at the start of a chunk that the cut
+	// point search never reads.
+	skip uint
+	// fingerprint identifies the configuration which determines the
+	// chunk boundaries.
+	fingerprint uint64
+	newHash     func() hash.Hash
+	// digester is used by the iterations holding the chunker.
+	digester digester
+	busy     atomic.Bool
 }
 
 // NewChunker returns a blazing fast chunker.
@@ -56,6 +129,15 @@ func NewChunker(opts ...Option) (*Chunker, error) {
 		opt(config)
 	}
 
+	if config.algorithm > RAM {
+		return nil, fmt.Errorf("unknown %s: %w", config.algorithm, ErrInvalidAlgorithm)
+	}
+	return newChunker(config)
+}
+
+// newChunker validates the configuration and returns a chunker with the
+// cut point algorithm of the configuration.
+func newChunker(config *config) (*Chunker, error) {
 	if config.bufferSize == 0 {
 		config.bufferSize = 2 * config.maxSize
 	}
@@ -95,19 +177,95 @@ func NewChunker(opts ...Option) (*Chunker, error) {
 	if config.maxSize-config.minSize <= config.avgSize {
 		return nil, fmt.Errorf("maximum - minimum chunk size must be bigger than the average chunk size: %w", ErrInvalidChunkSize)
 	}
+	if config.ronomon && (config.algorithm != FastCDC2016 || config.normalization != 1 || config.key != nil || config.table != nil) {
+		return nil, fmt.Errorf("ronomon compatibility requires %s, the normalization level 1 and the built-in gear table: %w", FastCDC2016, ErrInvalidAlgorithm)
+	}
+
+	c := &Chunker{
+		buffer:    make([]byte, config.bufferSize),
+		algorithm: config.algorithm,
+		minSize:   config.minSize,
+		avgSize:   config.avgSize,
+		maxSize:   config.maxSize,
+		skip:      config.minSize,
+		newHash:   config.newHash,
+	}
+	var err error
+	switch config.algorithm {
+	case rabin:
+		err = c.initRabin(config)
+	case buzhash:
+		err = c.initBuzhash(config)
+	case AE, RAM:
+		err = c.initExtremum(config)
+	default:
+		err = c.initGear(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.digester = c.newDigester()
+	c.fingerprint = c.configFingerprint()
+	return c, nil
+}
+
+// initGear sets up the gear table and the masks of the FastCDC
+// algorithms.
+func (c *Chunker) initGear(config *config) error {
+	if config.window != 0 || config.polynomial != 0 || config.seed != nil {
+		return fmt.Errorf("the window size, the polynomial and the seed only apply to the Rabin and buzhash chunkers: %w", ErrInvalidAlgorithm)
+	}
+
+	gear, gearLS := &table, tableLS
+	if config.key != nil {
+		if len(config.key) == 0 {
+			return fmt.Errorf("the key must not be empty: %w", ErrInvalidKey)
+		}
+		if config.table != nil {
+			return fmt.Errorf("a key and a custom gear table are mutually exclusive: %w", ErrInvalidGearTable)
+		}
+		gear = keyedTable(config.key)
+		gearLS = shiftTable(gear)
+	}
+	if config.table != nil {
+		if err := validateTable(config.table); err != nil {
+			return err
+		}
+		gear = config.table
+		gearLS = shiftTable(gear)
+	}
 
 	bits := logarithm2(config.avgSize)
 
-	return &Chunker{
-		buffer:  make([]byte, config.bufferSize),
-		minSize: config.minSize,
-		avgSize: config.avgSize,
-		maxSize: config.maxSize,
-		// Masks use 1 bit normalization.
-		// https://github.com/ronomon/deduplication#content-dependent-chunking
-		maskS: mask(bits + 1),
-		maskL: mask(bits - 1),
-	}, nil
+	// The strict mask has level more bits than the average chunk size,
+	// and the loose mask has level less bits, but masks must keep 1 to 31
+	// bits.
+	level := config.normalization
+	if level >= bits || bits+level > 31 {
+		return fmt.Errorf("the normalization level must be within [0, %d] for an average of %d: %w", min(bits-1, 31-bits), config.avgSize, ErrInvalidNormalization)
+	}
+	// https://github.com/ronomon/deduplication#content-dependent-chunking
+	c.maskS, c.maskL = mask(bits+level), mask(bits-level)
+	if config.algorithm == FastCDC2020 {
+		c.maskS, c.maskL = spreadMask(bits+level), spreadMask(bits-level)
+	}
+	c.table, c.tableLS = gear, gearLS
+	return nil
+}
+
+// Sizes returns the min, average and max chunk sizes of the chunker.
+func (c *Chunker) Sizes() (minSize, avgSize, maxSize uint) {
+	return c.minSize, c.avgSize, c.maxSize
+}
+
+// Fingerprint identifies the configuration which determines the chunk
+// boundaries: the algorithm, the chunk sizes, the normalization and the
+// gear table, or the polynomial, the table and the window size of the
+// Rabin and buzhash chunkers. Two chunkers with the same fingerprint
+// produce the same chunks. The buffer size and the digest have no impact
+// on it.
+func (c *Chunker) Fingerprint() uint64 {
+	return c.fingerprint
 }
 
 // Chunks returns an iterator that reads the stream and yields its chunks
@@ -116,13 +274,31 @@ func NewChunker(opts ...Option) (*Chunker, error) {
 // iterator yields a zero Chunk with the error and stops. The part of the
 // stream read so far but not yet chunked is dropped.
 //
-// The yielded Chunk.Data aliases the chunker's internal buffer. It is
-// only valid for the current iteration and must be copied for later
-// use.
+// The yielded Chunk.Data and Chunk.Sum alias the chunker's internal
+// buffers. They are only valid for the current iteration and must be
+// copied for later use.
 //
 // The chunker can be reused for another stream once the previous
 // iteration is over, but only one iteration must run at a time.
 func (c *Chunker) Chunks(r io.Reader) iter.Seq2[Chunk, error] {
+	return c.chunks(context.Background(), r, 0)
+}
+
+// ChunksContext is like Chunks but stops when ctx is done. The context
+// is checked before every read and before every yield. Once it is done,
+// the iterator yields a zero Chunk with ctx.Err() and stops, and the
+// chunker is released right away.
+//
+// A read that is already blocked in r.Read cannot be interrupted by the
+// chunker. To stop it, r must be unblocked by other means, for example
+// by closing it or setting a deadline on the underlying connection.
+func (c *Chunker) ChunksContext(ctx context.Context, r io.Reader) iter.Seq2[Chunk, error] {
+	return c.chunks(ctx, r, 0)
+}
+
+// chunks yields the chunks of the stream read from r, which starts at the
+// given stream position.
+func (c *Chunker) chunks(ctx context.Context, r io.Reader, offset int64) iter.Seq2[Chunk, error] {
 	return func(yield func(Chunk, error) bool) {
 		if !c.busy.CompareAndSwap(false, true) {
 			panic("fastcdc: chunker already in use")
@@ -130,11 +306,11 @@ func (c *Chunker) Chunks(r io.Reader) iter.Seq2[Chunk, error] {
 		defer c.busy.Store(false)
 
 		var (
-			offset int64 // stream position of buffer[0]
-			start  uint  // start of the current chunk in the buffer
-			end    uint  // end of the buffered data
-			eof    bool
+			start uint // start of the current chunk in the buffer
+			end   uint // end of the buffered data
+			eof   bool
 		)
+		// offset is the stream position of buffer[0].
 		for {
 			if !eof && end-start < c.maxSize {
 				// Move the pending bytes at the front of the buffer, then
@@ -147,6 +323,10 @@ func (c *Chunker) Chunks(r io.Reader) iter.Seq2[Chunk, error] {
 				end -= start
 				start = 0
 				for end < uint(len(c.buffer)) {
+					if err := ctx.Err(); err != nil {
+						yield(Chunk{}, err)
+						return
+					}
 					n, err := r.Read(c.buffer[end:])
 					end += uint(n)
 					if err == io.EOF {
@@ -164,13 +344,39 @@ func (c *Chunker) Chunks(r io.Reader) iter.Seq2[Chunk, error] {
 				return
 			}
 
-			length := c.breakpoint(c.buffer[start:end])
-			if length == 0 {
-				// No cut point in the pending bytes means this is the
-				// last chunk of the stream.
-				length = end - start
+			length, reason := c.nextCut(c.buffer[start:end])
+			if err := ctx.Err(); err != nil {
+				yield(Chunk{}, err)
+				return
+			}
+			data := c.buffer[start : start+length]
+			if !yield(Chunk{Offset: offset + int64(start), Data: data, Sum: c.digester.digest(data), Reason: reason}, nil) {
+				return
 			}
-			if !yield(Chunk{Offset: offset + int64(start), Data: c.buffer[start : start+length]}, nil) {
+			start += length
+		}
+	}
+}
+
+// ChunkBytes returns an iterator that yields the chunks of b in order.
+// It produces exactly the same chunks as Chunks(bytes.NewReader(b)), but
+// runs directly over b without copying it to the internal buffer.
+//
+// The yielded Chunk.Data is a sub-slice of b and stays valid after the
+// iteration, but Chunk.Sum is only valid for the current iteration. Since
+// the internal buffer is not used, ChunkBytes can run concurrently with
+// any other iteration on the same chunker.
+func (c *Chunker) ChunkBytes(b []byte) iter.Seq[Chunk] {
+	return func(yield func(Chunk) bool) {
+		d := c.newDigester()
+		var start uint
+		end := uint(len(b))
+		for start < end {
+			// The whole input is available, so every window has either at
+			// least max size bytes ahead or reaches the end of stream.
+			length, reason := c.nextCut(b[start:end])
+			data := b[start : start+length : start+length]
+			if !yield(Chunk{Offset: int64(start), Data: data, Sum: d.digest(data), Reason: reason}) {
 				return
 			}
 			start += length
@@ -178,14 +384,27 @@ func (c *Chunker) Chunks(r io.Reader) iter.Seq2[Chunk, error] {
 	}
 }
 
-// breakpoint returns the size of the next chunk in the window, or 0 when
-// no cut point can be found before the end of the window.
-func (c *Chunker) breakpoint(window []byte) uint {
+// nextCut returns the size of the next chunk in the window and the reason
+// of its cut. The window must hold at least max size bytes or reach the
+// end of stream: no cut point in the window means this is the last chunk
+// of the stream.
+func (c *Chunker) nextCut(window []byte) (uint, CutReason) {
+	length, reason := c.breakpoint(window)
+	if length == 0 {
+		return uint(len(window)), CutEOF
+	}
+	return length, reason
+}
+
+// breakpoint returns the size of the next chunk in the window and the
+// reason of its cut, or 0 when no cut point can be found before the end
+// of the window.
+func (c *Chunker) breakpoint(window []byte) (uint, CutReason) {
 	length := uint(len(window))
 
 	// Sub-minimum chunk cut-point skipping.
 	if length <= c.minSize {
-		return 0
+		return 0, CutNone
 	}
 
 	// Never look past max size bytes. Over that limit the chunk is cut
@@ -193,21 +412,58 @@ func (c *Chunker) breakpoint(window []byte) uint {
 	if length > c.maxSize {
 		length = c.maxSize
 	}
+	window = window[:length]
 
+	var (
+		cut    uint
+		reason CutReason
+	)
+	switch c.algorithm {
+	case FastCDC2020:
+		cut, reason = c.search2020(window)
+	case rabin:
+		cut, reason = c.searchRabin(window)
+	case buzhash:
+		cut, reason = c.searchBuzhash(window)
+	case AE:
+		cut, reason = c.searchAE(window)
+	case RAM:
+		cut, reason = c.searchRAM(window)
+	default:
+		cut, reason = c.search2016(window)
+	}
+	if cut != 0 {
+		return cut, reason
+	}
+
+	// We are unable to find a cut point. If the window is exactly max
+	// size long, the chunk reaches the max size allowed and must be cut.
+	if length == c.maxSize {
+		return length, CutMaxSize
+	}
+	return 0, CutNone
+}
+
+// search2016 is the cut point search of the FastCDC2016 algorithm. Like
+// the search of every algorithm, it gets a window longer than min size
+// and at most max size long, and returns the first cut point in it, or 0.
+func (c *Chunker) search2016(window []byte) (uint, CutReason) {
+	length := uint(len(window))
 	normalSize := centerSize(c.avgSize, c.minSize, length)
 
 	var hash uint64
 	cut := c.minSize
 	maskS := c.maskS
 	maskL := c.maskL
+	gear := c.table
 
 	// Start by using the "harder" chunking judgement to find
 	// chunks that run smaller than the desired normal size.
 	for cut < normalSize {
-		hash = (hash >> 1) + table[window[cut]]
+		hash = (hash >> 1) + gear[window[cut]]
 		cut++
 		if hash&maskS == 0 {
-			return cut
+			return cut, CutStrict
 		}
 	}
 
@@ -215,20 +471,13 @@ func (c *Chunker) breakpoint(window []byte) uint {
 	// that run larger than the desired normal size but never bigger than
 	// the max size.
 	for cut < length {
-		hash = (hash >> 1) + table[window[cut]]
+		hash = (hash >> 1) + gear[window[cut]]
 		cut++
 		if hash&maskL == 0 {
-			return cut
+			return cut, CutLoose
 		}
 	}
-
-	// We are unable to find a cut point with the chunking judgement.
-	// If the window is exactly max size long, the chunk reaches the max
-	// size allowed and must be cut.
-	if cut == c.maxSize {
-		return cut
-	}
-	return 0
+	return 0, CutNone
 }
 
 // centerSize finds the middle of the desired chunk size. This is what the
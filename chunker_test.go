@@ -2,10 +2,12 @@ package fastcdc
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/rand/v2"
 	"os"
 	"slices"
@@ -34,11 +36,14 @@ type chunkInfo struct {
 	Length int
 }
 
-var sekienGoldens = map[string]struct {
+// sekienGolden are the golden chunks of the Sekien fixture for a preset.
+type sekienGolden struct {
 	Preset  Option
 	MaxSize uint
 	Want    []chunkInfo
-}{
+}
+
+var sekienGoldens = map[string]sekienGolden{
 	"16kChunks": {
 		Preset:  With16kChunks(),
 		MaxSize: 131_072,
@@ -68,6 +73,29 @@ var sekienGoldens = map[string]struct {
 	},
 }
 
+// sekienAlgorithm is a cut point algorithm along with its golden chunks.
+type sekienAlgorithm struct {
+	New     func(opts ...Option) (*Chunker, error)
+	Goldens map[string]sekienGolden
+}
+
+// sekienAlgorithms are the algorithms the invariance tests run with.
+var sekienAlgorithms = map[string]sekienAlgorithm{
+	"fastcdc2016": {NewChunker, sekienGoldens},
+	"fastcdc2020": {withAlgorithm(FastCDC2020), sekien2020Goldens},
+	"ae":          {withAlgorithm(AE), sekienAEGoldens},
+	"ram":         {withAlgorithm(RAM), sekienRAMGoldens},
+	"rabin":       {newRabinChunker, sekienRabinGoldens},
+	"buzhash":     {newBuzhashChunker, sekienBuzhashGoldens},
+}
+
+// withAlgorithm returns a constructor of chunkers with the algorithm.
+func withAlgorithm(algo Algorithm) func(opts ...Option) (*Chunker, error) {
+	return func(opts ...Option) (*Chunker, error) {
+		return NewChunker(append(opts, WithAlgorithm(algo))...)
+	}
+}
+
 // chunkyReader delivers at most n bytes per read.
 type chunkyReader struct {
 	r io.Reader
@@ -371,7 +399,7 @@ func TestSekienChunks(t *testing.T) {
 }
 
 // TestSekienReaderFragmentation checks that the read pattern of the reader
-// has no impact on the chunk output.
+// has no impact on the chunk output, whatever the algorithm.
 func TestSekienReaderFragmentation(t *testing.T) {
 	data := sekienData(t)
 
@@ -384,24 +412,27 @@ func TestSekienReaderFragmentation(t *testing.T) {
 		"1000 bytes": func(r io.Reader) io.Reader { return &chunkyReader{r, 1000} },
 	}
 
-	for name, tc := range sekienGoldens {
-		t.Run(name, func(t *testing.T) {
-			chunker, err := NewChunker(tc.Preset)
-			if err != nil {
-				t.Fatal(err)
-			}
-			for readerName, wrap := range readers {
-				chunks := chunkAll(t, chunker, wrap(bytes.NewReader(data)), data)
-				if !slices.Equal(chunks, tc.Want) {
-					t.Errorf("%s reader: chunks: want = %v, got = %v", readerName, tc.Want, chunks)
+	for algo, a := range sekienAlgorithms {
+		for name, tc := range a.Goldens {
+			t.Run(algo+"/"+name, func(t *testing.T) {
+				chunker, err := a.New(tc.Preset)
+				if err != nil {
+					t.Fatal(err)
 				}
-			}
-		})
+				for readerName, wrap := range readers {
+					chunks := chunkAll(t, chunker, wrap(bytes.NewReader(data)), data)
+					if !slices.Equal(chunks, tc.Want) {
+						t.Errorf("%s reader: chunks: want = %v, got = %v", readerName, tc.Want, chunks)
+					}
+				}
+			})
+		}
 	}
 }
 
 // TestSekienBufferSizeInvariance checks that the buffer size has no impact
-// on the chunk output, whatever the read pattern of the reader.
+// on the chunk output, whatever the algorithm and the read pattern of the
+// reader.
 func TestSekienBufferSizeInvariance(t *testing.T) {
 	data := sekienData(t)
 
@@ -409,22 +440,24 @@ func TestSekienBufferSizeInvariance(t *testing.T) {
 	rng := rand.New(rand.NewPCG(uint64(seed), 0))
 	t.Logf("seed: %d", seed)
 
-	for name, tc := range sekienGoldens {
-		t.Run(name, func(t *testing.T) {
-			for range 200 {
-				bufSize := tc.MaxSize + uint(rng.IntN(int(1<<20-tc.MaxSize)+1))
-				frag := 1 + rng.IntN(1<<17)
+	for algo, a := range sekienAlgorithms {
+		for name, tc := range a.Goldens {
+			t.Run(algo+"/"+name, func(t *testing.T) {
+				for range 200 {
+					bufSize := tc.MaxSize + uint(rng.IntN(int(1<<20-tc.MaxSize)+1))
+					frag := 1 + rng.IntN(1<<17)
 
-				chunker, err := NewChunker(tc.Preset, WithBufferSize(bufSize))
-				if err != nil {
-					t.Fatal(err)
-				}
-				chunks := chunkAll(t, chunker, &chunkyReader{bytes.NewReader(data), frag}, data)
-				if !slices.Equal(chunks, tc.Want) {
-					t.Fatalf("chunks: want = %v, got = %v, buffer size = %d, read size = %d", tc.Want, chunks, bufSize, frag)
+					chunker, err := a.New(tc.Preset, WithBufferSize(bufSize))
+					if err != nil {
+						t.Fatal(err)
+					}
+					chunks := chunkAll(t, chunker, &chunkyReader{bytes.NewReader(data), frag}, data)
+					if !slices.Equal(chunks, tc.Want) {
+						t.Fatalf("chunks: want = %v, got = %v, buffer size = %d, read size = %d", tc.Want, chunks, bufSize, frag)
+					}
 				}
-			}
-		})
+			})
+		}
 	}
 }
 
@@ -433,14 +466,21 @@ func TestSekienBufferSizeInvariance(t *testing.T) {
 // the read pattern of the reader.
 func TestRandomInput(t *testing.T) {
 	tests := []struct {
-		Name    string
-		MinSize int
-		MaxSize int
-		Opt     Option
+		Name       string
+		MinSize    int
+		MaxSize    int
+		Iterations int
+		Opts       []Option
 	}{
-		{"16kChunks", 4096, 131_072, With16kChunks()},
-		{"32kChunks", 8192, 262_144, With32kChunks()},
-		{"64kChunks", 16_384, 524_288, With64kChunks()},
+		{"16kChunks", 4096, 131_072, 150, []Option{With16kChunks()}},
+		{"32kChunks", 8192, 262_144, 150, []Option{With32kChunks()}},
+		{"64kChunks", 16_384, 524_288, 150, []Option{With64kChunks()}},
+		// The other algorithms share the buffering and the reading
+		// code, fewer iterations cover their cut points.
+		{"16kChunks2020", 4096, 131_072, 20, []Option{With16kChunks(), WithAlgorithm(FastCDC2020)}},
+		{"64kChunks2020", 16_384, 524_288, 20, []Option{With64kChunks(), WithAlgorithm(FastCDC2020)}},
+		{"16kChunksAE", 4096, 131_072, 20, []Option{With16kChunks(), WithAlgorithm(AE)}},
+		{"16kChunksRAM", 4096, 131_072, 20, []Option{With16kChunks(), WithAlgorithm(RAM)}},
 	}
 
 	seed := time.Now().UnixNano()
@@ -449,12 +489,12 @@ func TestRandomInput(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.Name, func(t *testing.T) {
-			reference, err := NewChunker(tc.Opt)
+			reference, err := NewChunker(tc.Opts...)
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			for range 150 {
+			for range tc.Iterations {
 				size := 1000 + rng.IntN(4<<20)
 				data := make([]byte, size)
 				for i := range data {
@@ -474,7 +514,7 @@ func TestRandomInput(t *testing.T) {
 				bufSize := uint(tc.MaxSize) + uint(rng.IntN(1<<20-tc.MaxSize+1))
 				frag := 512 + rng.IntN(1<<17)
 
-				chunker, err := NewChunker(tc.Opt, WithBufferSize(bufSize))
+				chunker, err := NewChunker(append(tc.Opts, WithBufferSize(bufSize))...)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -558,6 +598,107 @@ func TestEarlyBreakAndReuse(t *testing.T) {
 	}
 }
 
+// cancelingReader cancels the context once it has delivered n bytes.
+type cancelingReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n -= n
+	if c.n <= 0 {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestChunksContext(t *testing.T) {
+	data := randomData(13, 4<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+	var chunks []chunkInfo
+	for chunk, err := range chunker.ChunksContext(context.Background(), bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+	}
+	if !slices.Equal(chunks, want) {
+		t.Errorf("chunks: want = %v, got = %v", want, chunks)
+	}
+}
+
+func TestChunksContextCancel(t *testing.T) {
+	data := randomData(13, 4<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]func(ctx context.Context, cancel context.CancelFunc) io.Reader{
+		"before iteration": func(ctx context.Context, cancel context.CancelFunc) io.Reader {
+			cancel()
+			return bytes.NewReader(data)
+		},
+		"between reads": func(ctx context.Context, cancel context.CancelFunc) io.Reader {
+			return &cancelingReader{r: &chunkyReader{bytes.NewReader(data), 1000}, n: 100_000, cancel: cancel}
+		},
+		"between yields": func(ctx context.Context, cancel context.CancelFunc) io.Reader {
+			return &cancelingReader{r: bytes.NewReader(data), n: len(data), cancel: cancel}
+		},
+	}
+
+	for name, newReader := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var gotErr error
+			for chunk, err := range chunker.ChunksContext(ctx, newReader(ctx, cancel)) {
+				if err != nil {
+					gotErr = err
+					if chunk.Offset != 0 || len(chunk.Data) != 0 {
+						t.Error("chunk must be zero when an error is yielded")
+					}
+					continue
+				}
+			}
+			if !errors.Is(gotErr, context.Canceled) {
+				t.Errorf("want = %s, got = %s", context.Canceled, gotErr)
+			}
+
+			// The chunker is released right away.
+			chunkAll(t, chunker, bytes.NewReader(data), data)
+		})
+	}
+}
+
+func TestChunksContextDeadline(t *testing.T) {
+	chunker, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	var gotErr error
+	for _, err := range chunker.ChunksContext(ctx, bytes.NewReader(sekienData(t))) {
+		gotErr = err
+	}
+	if !errors.Is(gotErr, context.DeadlineExceeded) {
+		t.Errorf("want = %s, got = %s", context.DeadlineExceeded, gotErr)
+	}
+}
+
 func TestConcurrentIterationPanic(t *testing.T) {
 	data := sekienData(t)
 
@@ -650,3 +791,237 @@ func TestSekienMaxChunks(t *testing.T) {
 		t.Errorf("want offset = 0, got offset = %d, want length = 109466, got length = %d", chunks[0].Offset, chunks[0].Length)
 	}
 }
+
+func TestSekienChunkBytes(t *testing.T) {
+	data := sekienData(t)
+
+	for name, tc := range sekienGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []Chunk
+			for chunk := range chunker.ChunkBytes(data) {
+				got = append(got, chunk)
+			}
+
+			// The chunks alias the input and stay valid after the iteration.
+			var chunks []chunkInfo
+			var pos int64
+			for _, chunk := range got {
+				if !bytes.Equal(chunk.Data, data[pos:pos+int64(len(chunk.Data))]) {
+					t.Fatalf("chunk content mismatch at offset %d", chunk.Offset)
+				}
+				chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+				pos += int64(len(chunk.Data))
+			}
+			if !slices.Equal(chunks, tc.Want) {
+				t.Errorf("chunks: want = %v, got = %v", tc.Want, chunks)
+			}
+		})
+	}
+}
+
+// TestChunkBytesRandomInput checks on random input that ChunkBytes
+// produces the same chunks as Chunks.
+func TestChunkBytesRandomInput(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 50 {
+		data := randomData(rng.Uint64(), rng.IntN(2<<20))
+		want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+		var got []chunkInfo
+		for chunk := range chunker.ChunkBytes(data) {
+			got = append(got, chunkInfo{chunk.Offset, len(chunk.Data)})
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("chunks: want = %v, got = %v, input size = %d", want, got, len(data))
+		}
+	}
+}
+
+func TestChunkBytesEmptyInput(t *testing.T) {
+	chunker, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range chunker.ChunkBytes(nil) {
+		t.Error("no chunk expected on empty input")
+	}
+}
+
+// TestNormalizationDistribution checks that the chunk size distribution
+// tightens around the average as the normalization level goes up.
+func TestNormalizationDistribution(t *testing.T) {
+	data := randomData(29, 32<<20)
+
+	prev := math.Inf(1)
+	for level := range uint(4) {
+		chunker, err := NewChunker(WithChunksSize(4096, 16_384, 131_072), WithNormalization(level))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n, sum, sumSq float64
+		for chunk := range chunker.ChunkBytes(data) {
+			size := float64(len(chunk.Data))
+			n++
+			sum += size
+			sumSq += size * size
+		}
+		mean := sum / n
+		stddev := math.Sqrt(sumSq/n - mean*mean)
+		t.Logf("level %d: chunks = %.0f, mean = %.0f, stddev = %.0f", level, n, mean, stddev)
+
+		if stddev >= prev {
+			t.Errorf("level %d: stddev: want < %.0f, got = %.0f", level, prev, stddev)
+		}
+		prev = stddev
+	}
+}
+
+func TestNormalizationValidation(t *testing.T) {
+	tests := map[string]struct {
+		AvgSize uint
+		Level   uint
+		Want    error
+	}{
+		"no normalization":       {AvgSize: 65_536, Level: 0, Want: nil},
+		"level 3":                {AvgSize: 65_536, Level: 3, Want: nil},
+		"strict mask too large":  {AvgSize: AverageMax, Level: 4, Want: ErrInvalidNormalization},
+		"loose mask too small":   {AvgSize: AverageMin, Level: 8, Want: ErrInvalidNormalization},
+		"highest level for 256":  {AvgSize: AverageMin, Level: 7, Want: nil},
+		"highest level for 2^28": {AvgSize: AverageMax, Level: 3, Want: nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Size max and the buffer after the average, not MaximumMax.
+			maxSize := max(2*tc.AvgSize+MinimumMin, MaximumMin)
+			_, err := NewChunker(WithChunksSize(MinimumMin, tc.AvgSize, maxSize), WithBufferSize(maxSize), WithNormalization(tc.Level))
+			if !errors.Is(err, tc.Want) {
+				t.Errorf("want = %v, got = %v", tc.Want, err)
+			}
+		})
+	}
+}
+
+func TestAllZerosCutReason(t *testing.T) {
+	input := make([]byte, 10240+100)
+	chunker, err := NewChunker(WithChunksSize(64, 256, 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reasons []CutReason
+	for chunk, err := range chunker.Chunks(bytes.NewReader(input)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		reasons = append(reasons, chunk.Reason)
+	}
+	want := append(slices.Repeat([]CutReason{CutMaxSize}, 10), CutEOF)
+	if !slices.Equal(reasons, want) {
+		t.Errorf("reasons: want = %v, got = %v", want, reasons)
+	}
+}
+
+// TestCutReason checks on random input that the cut reasons match the
+// chunk sizes, and that every way of chunking a stream reports the same
+// reasons.
+func TestCutReason(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	configs := [][3]uint{
+		{64, 256, 1024},
+		{4096, 16_384, 131_072},
+		{32_768, 65_536, 131_072},
+	}
+
+	for range 20 {
+		cfg := configs[rng.IntN(len(configs))]
+		algo := Algorithm(rng.IntN(2))
+		chunker, err := NewChunker(WithChunksSize(cfg[0], cfg[1], cfg[2]), WithAlgorithm(algo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := randomData(rng.Uint64(), rng.IntN(2<<20))
+		normalSize := int(centerSize(cfg[1], cfg[0], cfg[2]))
+
+		var want []CutReason
+		for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := len(chunk.Data)
+			last := chunk.Offset+int64(n) == int64(len(data))
+			var ok bool
+			switch chunk.Reason {
+			case CutStrict:
+				ok = n > int(cfg[0]) && n <= normalSize
+			case CutLoose:
+				ok = n > normalSize || (last && n > int(cfg[0]))
+			case CutMaxSize:
+				ok = n == int(cfg[2])
+			case CutEOF:
+				ok = last && n < int(cfg[2])
+			}
+			if !ok {
+				t.Fatalf("%s, config %v: chunk of %d bytes at offset %d cut by %s", algo, cfg, n, chunk.Offset, chunk.Reason)
+			}
+			want = append(want, chunk.Reason)
+		}
+
+		paths := map[string]func(yield func(CutReason)){
+			"ChunkBytes": func(yield func(CutReason)) {
+				for chunk := range chunker.ChunkBytes(data) {
+					yield(chunk.Reason)
+				}
+			},
+			"Writer": func(yield func(CutReason)) {
+				w := chunker.NewWriter(func(chunk Chunk) error {
+					yield(chunk.Reason)
+					return nil
+				})
+				_, _ = w.Write(data)
+				_ = w.Close()
+			},
+			"Boundaries": func(yield func(CutReason)) {
+				for b, err := range chunker.Boundaries(bytes.NewReader(data)) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					yield(b.Reason)
+				}
+			},
+			"ChunkReaderAt": func(yield func(CutReason)) {
+				for chunk, err := range chunker.chunkReaderAt(bytes.NewReader(data), int64(len(data)), 4, 100_000) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					yield(chunk.Reason)
+				}
+			},
+		}
+		for name, path := range paths {
+			var got []CutReason
+			path(func(r CutReason) { got = append(got, r) })
+			if !slices.Equal(got, want) {
+				t.Fatalf("%s, config %v, %s: reasons: want = %v, got = %v", algo, cfg, name, want, got)
+			}
+		}
+	}
+}
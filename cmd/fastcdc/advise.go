@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+type adviceRecord struct {
+	estimationRecord
+	OverheadPerTB float64 `json:"overhead_per_tb"`
+	StoredBytes   int64   `json:"stored_bytes"`
+	MeetsGoals    bool    `json:"meets_goals"`
+}
+
+func runAdvise(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	var cf chunkerFlags
+	var goals fastcdc.Goals
+	fs := newFlagSet("advise", "dir", stderr, nil)
+	cf.registerCommon(fs)
+	fs.Float64Var(&goals.MinDedupRatio, "min-ratio", 0, "minimum dedup ratio")
+	fs.Int64Var(&goals.MaxOverheadPerTB, "max-overhead", 0, "maximum chunk metadata bytes per TB of data")
+	fs.Int64Var(&goals.MaxChunks, "max-chunks", 0, "maximum number of chunks of the sample")
+	fs.IntVar(&goals.ChunkMetadataSize, "metadata-size", 48, "metadata bytes per chunk")
+	fs.UintVar(&goals.MinAvgSize, "min-avg", 1<<10, "smallest average chunk size searched")
+	fs.UintVar(&goals.MaxAvgSize, "max-avg", 256<<10, "largest average chunk size searched")
+	top := fs.Int("top", 10, "number of configurations printed, 0 for all")
+	asJSON := fs.Bool("json", false, "print the configurations as JSON")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	opts, err := cf.commonOptions()
+	if err != nil {
+		return err
+	}
+	advices, err := fastcdc.Advise(os.DirFS(fs.Arg(0)), goals, opts...)
+	if err != nil {
+		return err
+	}
+	if *top > 0 && *top < len(advices) {
+		advices = advices[:*top]
+	}
+
+	records := make([]adviceRecord, len(advices))
+	for i, a := range advices {
+		e := a.Estimation
+		records[i] = adviceRecord{
+			estimationRecord{e.MinSize, e.AvgSize, e.MaxSize, e.Files, e.Chunks, e.UniqueChunks, e.Bytes, e.UniqueBytes, e.DedupRatio()},
+			a.OverheadPerTB, a.StoredBytes, a.MeetsGoals,
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "rank\tmin/avg/max\tchunks\tdedup ratio\toverhead/TB\tstored bytes\tgoals")
+	for i, r := range records {
+		status := "met"
+		if !r.MeetsGoals {
+			status = "missed"
+		}
+		fmt.Fprintf(tw, "%d\t%d/%d/%d\t%d\t%.3f\t%.0f\t%d\t%s\n", i+1, r.MinSize, r.AvgSize, r.MaxSize, r.Chunks, r.DedupRatio, r.OverheadPerTB, r.StoredBytes, status)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type chunkRecord struct {
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+	Digest string `json:"digest,omitempty"`
+}
+
+func runChunk(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var cf chunkerFlags
+	fs := newFlagSet("chunk", "file", stderr, &cf)
+	digest := fs.String("digest", "sha256", "chunk digest: none, sha256, sha512_256 or crc64")
+	asJSON := fs.Bool("json", false, "print one JSON object per chunk")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	opts, err := cf.options()
+	if err != nil {
+		return err
+	}
+	digestOpt, err := digestOption(*digest)
+	if err != nil {
+		return err
+	}
+	if digestOpt != nil {
+		opts = append(opts, digestOpt)
+	}
+	chunker, err := cf.newSplitter(opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := open(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(stdout)
+	enc := json.NewEncoder(w)
+	for chunk, err := range chunker.Chunks(f) {
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			if err := enc.Encode(chunkRecord{chunk.Offset, len(chunk.Data), hex.EncodeToString(chunk.Sum)}); err != nil {
+				return err
+			}
+			continue
+		}
+		if chunk.Sum != nil {
+			fmt.Fprintf(w, "%d\t%d\t%x\n", chunk.Offset, len(chunk.Data), chunk.Sum)
+		} else {
+			fmt.Fprintf(w, "%d\t%d\n", chunk.Offset, len(chunk.Data))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+type fileSummary struct {
+	Name   string `json:"name"`
+	Chunks int    `json:"chunks"`
+	Bytes  int64  `json:"bytes"`
+	// SharedChunks and SharedBytes count the chunks of the file which are
+	// also chunks of the other file.
+	SharedChunks int   `json:"shared_chunks"`
+	SharedBytes  int64 `json:"shared_bytes"`
+}
+
+func runCompare(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var cf chunkerFlags
+	fs := newFlagSet("compare", "file1 file2", stderr, &cf)
+	asJSON := fs.Bool("json", false, "print the comparison as JSON")
+	if err := parse(fs, args, 2); err != nil {
+		return err
+	}
+	if fs.Arg(0) == "-" && fs.Arg(1) == "-" {
+		return fmt.Errorf("cannot read both files from the standard input")
+	}
+
+	opts, err := cf.options()
+	if err != nil {
+		return err
+	}
+	chunker, err := cf.newSplitter(opts)
+	if err != nil {
+		return err
+	}
+
+	var summaries [2]fileSummary
+	var digests [2]map[store.Digest]occurrences
+	for i := range summaries {
+		summaries[i].Name = fs.Arg(i)
+		digests[i], err = chunkDigests(chunker, fs.Arg(i), stdin, &summaries[i])
+		if err != nil {
+			return err
+		}
+	}
+	for i := range summaries {
+		for d, o := range digests[i] {
+			if _, ok := digests[1-i][d]; ok {
+				summaries[i].SharedChunks += o.count
+				summaries[i].SharedBytes += int64(o.count) * int64(o.length)
+			}
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+	for _, s := range summaries {
+		fmt.Fprintf(stdout, "%s: %d chunks, %d bytes, %d shared chunks, %d shared bytes (%.1f%%)\n",
+			s.Name, s.Chunks, s.Bytes, s.SharedChunks, s.SharedBytes, percent(s.SharedBytes, s.Bytes))
+	}
+	return nil
+}
+
+// occurrences are the occurrences of a chunk in a file.
+type occurrences struct {
+	length int
+	count  int
+}
+
+// chunkDigests returns the occurrences of the chunks of the named file by
+// digest, and counts its chunks in s.
+func chunkDigests(chunker fastcdc.Splitter, name string, stdin io.Reader, s *fileSummary) (map[store.Digest]occurrences, error) {
+	f, err := open(name, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digests := make(map[store.Digest]occurrences)
+	for chunk, err := range chunker.Chunks(f) {
+		if err != nil {
+			return nil, err
+		}
+		d := store.Sum(chunk.Data)
+		digests[d] = occurrences{length: len(chunk.Data), count: digests[d].count + 1}
+		s.Chunks++
+		s.Bytes += int64(len(chunk.Data))
+	}
+	return digests, nil
+}
+
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+type estimationRecord struct {
+	MinSize      uint    `json:"min"`
+	AvgSize      uint    `json:"avg"`
+	MaxSize      uint    `json:"max"`
+	Files        int     `json:"files"`
+	Chunks       int64   `json:"chunks"`
+	UniqueChunks int64   `json:"unique_chunks"`
+	Bytes        int64   `json:"bytes"`
+	UniqueBytes  int64   `json:"unique_bytes"`
+	DedupRatio   float64 `json:"dedup_ratio"`
+}
+
+func runEstimate(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	var cf chunkerFlags
+	fs := newFlagSet("estimate", "dir", stderr, nil)
+	cf.registerCommon(fs)
+	sizes := fs.String("configs", "16k,32k,64k", "comma-separated chunk sizes to compare: 16k, 32k, 64k or min/avg/max")
+	asJSON := fs.Bool("json", false, "print the estimations as JSON")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	common, err := cf.commonOptions()
+	if err != nil {
+		return err
+	}
+	var configs [][]fastcdc.Option
+	for s := range strings.SplitSeq(*sizes, ",") {
+		opt, err := parseSizes(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		configs = append(configs, append([]fastcdc.Option{opt}, common...))
+	}
+
+	estimations, err := fastcdc.Estimate(os.DirFS(fs.Arg(0)), configs...)
+	if err != nil {
+		return err
+	}
+
+	records := make([]estimationRecord, len(estimations))
+	for i, e := range estimations {
+		records[i] = estimationRecord{e.MinSize, e.AvgSize, e.MaxSize, e.Files, e.Chunks, e.UniqueChunks, e.Bytes, e.UniqueBytes, e.DedupRatio()}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "min/avg/max\tfiles\tchunks\tunique chunks\tbytes\tunique bytes\tdedup ratio")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%d/%d/%d\t%d\t%d\t%d\t%d\t%d\t%.3f\n", r.MinSize, r.AvgSize, r.MaxSize, r.Files, r.Chunks, r.UniqueChunks, r.Bytes, r.UniqueBytes, r.DedupRatio)
+	}
+	return tw.Flush()
+}
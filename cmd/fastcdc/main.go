@@ -0,0 +1,97 @@
+// Command fastcdc shows how files are split by the FastCDC chunker.
+//
+// Usage:
+//
+//	fastcdc chunk [flags] file
+//	fastcdc stats [flags] file
+//	fastcdc compare [flags] file1 file2
+//	fastcdc estimate [flags] dir
+//	fastcdc advise [flags] dir
+//
+// The chunk command prints the offset, the length and the digest of
+// every chunk, the stats command prints the chunk count, the chunk sizes
+// and their histogram, the compare command prints the chunks and the
+// bytes shared by two files, the estimate command prints the
+// deduplication ratio of several chunk sizes over the files of a
+// directory, and the advise command searches the chunk sizes which meet
+// deduplication and metadata goals over the files of a directory. A file
+// named "-" is read from the standard input. Every command accepts the
+// options of the chunker as flags; run "fastcdc <command> -h" for the
+// list.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage:
+	fastcdc chunk [flags] file
+	fastcdc stats [flags] file
+	fastcdc compare [flags] file1 file2
+	fastcdc estimate [flags] dir
+	fastcdc advise [flags] dir
+
+Run "fastcdc <command> -h" for the flags of a command.
+`
+
+var errUsage = errors.New("usage error")
+
+type command func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"chunk":    runChunk,
+	"stats":    runStats,
+	"compare":  runCompare,
+	"estimate": runEstimate,
+	"advise":   runAdvise,
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "fastcdc:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+		return errUsage
+	}
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+// open opens the named file, or the standard input if name is "-".
+func open(name string, stdin io.Reader) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(stdin), nil
+	}
+	return os.Open(name)
+}
+
+// parse parses the flags of a command, which takes nargs arguments.
+func parse(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+const sekienPath = "../../fixtures/SekienAkashita.jpg"
+
+func runOutput(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(args, strings.NewReader(""), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestChunk(t *testing.T) {
+	out, err := runOutput(t, "chunk", "-min", "8192", "-avg", "16384", "-max", "32768", "-digest", "none", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0\t22366\n22366\t8282\n30648\t16303\n46951\t18696\n65647\t32768\n98415\t11051\n"
+	if out != want {
+		t.Errorf("want = %q, got = %q", want, out)
+	}
+
+	out, err = runOutput(t, "chunk", "-preset", "16k", "-json", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(strings.NewReader(out))
+	var first chunkRecord
+	if err := dec.Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Offset != 0 || first.Length != 22366 || len(first.Digest) != 64 {
+		t.Errorf("first chunk: got = %v", first)
+	}
+
+	out, err = runOutput(t, "chunk", "-rabin", "-preset", "16k", "-digest", "none", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "0\t21168\n21168\t41245\n62413\t20639\n83052\t26414\n"
+	if out != want {
+		t.Errorf("want = %q, got = %q", want, out)
+	}
+
+	out, err = runOutput(t, "chunk", "-algorithm", "ram", "-preset", "32k", "-digest", "none", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "0\t32793\n32793\t32800\n65593\t32862\n98455\t11011\n"
+	if out != want {
+		t.Errorf("want = %q, got = %q", want, out)
+	}
+
+	out, err = runOutput(t, "chunk", "-buzhash", "-seed", "0", "-preset", "16k", "-digest", "none", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "0\t21859\n21859\t23062\n44921\t4341\n49262\t12770\n62032\t37127\n99159\t10307\n"
+	if out != want {
+		t.Errorf("want = %q, got = %q", want, out)
+	}
+}
+
+func TestStats(t *testing.T) {
+	out, err := runOutput(t, "stats", "-min", "8192", "-avg", "16384", "-max", "32768", "-json", sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Count       int64                 `json:"count"`
+		Bytes       int64                 `json:"bytes"`
+		Min         int                   `json:"min"`
+		Max         int                   `json:"max"`
+		LooseCuts   int64                 `json:"loose_cuts"`
+		MaxSizeCuts int64                 `json:"max_size_cuts"`
+		Histogram   []fastcdc.StatsBucket `json:"histogram"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Count != 6 || report.Bytes != 109466 || report.Min != 8282 || report.Max != 32768 || report.MaxSizeCuts != 1 || report.LooseCuts != 4 {
+		t.Errorf("got = %+v", report)
+	}
+	var total int64
+	for _, b := range report.Histogram {
+		total += b.Count
+	}
+	if total != report.Count {
+		t.Errorf("histogram count: want = %d, got = %d", report.Count, total)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	data, err := os.ReadFile(sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second half of the file, preceded by other bytes.
+	other := append(bytes.Repeat([]byte{'x'}, 1000), data[len(data)/2:]...)
+	path := t.TempDir() + "/other"
+	if err := os.WriteFile(path, other, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runOutput(t, "compare", "-preset", "16k", "-json", sekienPath, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summaries [2]fileSummary
+	if err := json.Unmarshal([]byte(out), &summaries); err != nil {
+		t.Fatal(err)
+	}
+	if summaries[0].Bytes != int64(len(data)) || summaries[1].Bytes != int64(len(other)) {
+		t.Errorf("bytes: got = %d, %d", summaries[0].Bytes, summaries[1].Bytes)
+	}
+	if summaries[0].SharedBytes == 0 || summaries[0].SharedBytes != summaries[1].SharedBytes {
+		t.Errorf("shared bytes: got = %d, %d", summaries[0].SharedBytes, summaries[1].SharedBytes)
+	}
+}
+
+func TestOptionsErrors(t *testing.T) {
+	tests := map[string][]string{
+		"unknown command":      {"bogus"},
+		"no command":           {},
+		"missing file":         {"chunk"},
+		"unknown flag":         {"chunk", "-bogus", sekienPath},
+		"preset and sizes":     {"chunk", "-preset", "16k", "-min", "64", "-avg", "256", "-max", "1024", sekienPath},
+		"unknown preset":       {"chunk", "-preset", "8k", sekienPath},
+		"unknown algorithm":    {"chunk", "-algorithm", "rabin", sekienPath},
+		"unknown digest":       {"chunk", "-digest", "md5", sekienPath},
+		"invalid sizes":        {"chunk", "-min", "64", "-avg", "32", "-max", "1024", sekienPath},
+		"gear table file":      {"stats", "-gear-table", sekienPath, sekienPath},
+		"both stdin":           {"compare", "-", "-"},
+		"ronomon and 2020":     {"chunk", "-ronomon", "-algorithm", "fastcdc2020", sekienPath},
+		"window without rabin": {"chunk", "-window", "32", sekienPath},
+		"reducible polynomial": {"chunk", "-rabin", "-polynomial", "0x5", sekienPath},
+		"rabin and buzhash":    {"chunk", "-rabin", "-buzhash", sekienPath},
+		"seed without buzhash": {"chunk", "-seed", "1", sekienPath},
+		"invalid seed":         {"chunk", "-buzhash", "-seed", "0x100000000", sekienPath},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := runOutput(t, args...); err == nil {
+				t.Error("want an error, got = nil")
+			}
+		})
+	}
+
+	if _, err := runOutput(t, "chunk", "-h"); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("want = %s, got = %v", flag.ErrHelp, err)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	data, err := os.ReadFile(sekienPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.WriteFile(dir+"/"+name, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runOutput(t, "estimate", "-json", "-configs", "16k,8192/16384/32768", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []estimationRecord
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].MinSize != 8192 || records[1].MaxSize != 32768 {
+		t.Fatalf("got = %+v", records)
+	}
+	for _, r := range records {
+		if r.Files != 2 || r.Bytes != 2*int64(len(data)) || r.DedupRatio != 2 {
+			t.Errorf("got = %+v", r)
+		}
+	}
+
+	if _, err := runOutput(t, "estimate", "-configs", "16k,8k", dir); err == nil {
+		t.Error("unknown preset: want an error, got = nil")
+	}
+}
+
+func TestAdvise(t *testing.T) {
+	out, err := runOutput(t, "advise", "-json", "-top", "3", "-min-avg", "4096", "-max-avg", "65536", "-max-chunks", "10", "../../fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []adviceRecord
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records: want = 3, got = %d", len(records))
+	}
+	for _, r := range records {
+		if !r.MeetsGoals || r.Chunks > 10 || r.AvgSize < 4096 || r.AvgSize > 65536 {
+			t.Errorf("got = %+v", r)
+		}
+	}
+}
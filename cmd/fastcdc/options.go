@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+// chunkerFlags are the flags of the chunker options.
+type chunkerFlags struct {
+	preset        string
+	minSize       uint
+	avgSize       uint
+	maxSize       uint
+	bufferSize    uint
+	algorithm     string
+	normalization uint
+	key           string
+	gearTable     string
+	ronomon       bool
+	rabin         bool
+	polynomial    uint64
+	window        uint
+	buzhash       bool
+	seed          *uint32
+}
+
+func (f *chunkerFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.preset, "preset", "", "chunk sizes preset: 16k, 32k, 64k or min/avg/max (default 64k)")
+	fs.UintVar(&f.minSize, "min", 0, "minimum chunk size, along with -avg and -max")
+	fs.UintVar(&f.avgSize, "avg", 0, "average chunk size, along with -min and -max")
+	fs.UintVar(&f.maxSize, "max", 0, "maximum chunk size, along with -min and -avg")
+	fs.BoolVar(&f.rabin, "rabin", false, "use the Rabin fingerprint chunker instead of FastCDC")
+	fs.Uint64Var(&f.polynomial, "polynomial", 0, "irreducible polynomial of the Rabin chunker (default 0x3DA3358B4DC173)")
+	fs.BoolVar(&f.buzhash, "buzhash", false, "use the buzhash chunker instead of FastCDC")
+	fs.Func("seed", "seed of the buzhash table (default 0)", func(s string) error {
+		seed, err := strconv.ParseUint(s, 0, 32)
+		if err != nil {
+			return err
+		}
+		v := uint32(seed)
+		f.seed = &v
+		return nil
+	})
+	fs.UintVar(&f.window, "window", 0, "rolling window size of the Rabin (default 64) and buzhash (default 4095) chunkers")
+	f.registerCommon(fs)
+}
+
+// registerCommon registers the flags of the options other than the
+// chunk sizes.
+func (f *chunkerFlags) registerCommon(fs *flag.FlagSet) {
+	fs.UintVar(&f.bufferSize, "buffer", 0, "buffer size (default 2 * max size)")
+	fs.StringVar(&f.algorithm, "algorithm", fastcdc.FastCDC2016.String(), "cut point algorithm: fastcdc2016, fastcdc2020, ae or ram")
+	fs.UintVar(&f.normalization, "normalization", 1, "normalization level")
+	fs.StringVar(&f.key, "key", "", "secret key of the gear table")
+	fs.StringVar(&f.gearTable, "gear-table", "", "file of a custom gear table, as 256 little-endian uint64")
+	fs.BoolVar(&f.ronomon, "ronomon", false, "guarantee the cut points of ronomon/deduplication")
+}
+
+// options returns the chunker options set by the flags.
+func (f *chunkerFlags) options() ([]fastcdc.Option, error) {
+	var opts []fastcdc.Option
+
+	if f.minSize != 0 || f.avgSize != 0 || f.maxSize != 0 {
+		if f.preset != "" {
+			return nil, errors.New("-preset and -min, -avg, -max are mutually exclusive")
+		}
+		opts = append(opts, fastcdc.WithChunksSize(f.minSize, f.avgSize, f.maxSize))
+	} else {
+		preset, err := parseSizes(f.preset)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, preset)
+	}
+
+	common, err := f.commonOptions()
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, common...), nil
+}
+
+// newSplitter returns the chunker selected by the flags, configured with
+// opts.
+func (f *chunkerFlags) newSplitter(opts []fastcdc.Option) (fastcdc.Splitter, error) {
+	if f.polynomial != 0 {
+		opts = append(opts, fastcdc.WithPolynomial(f.polynomial))
+	}
+	if f.window != 0 {
+		opts = append(opts, fastcdc.WithWindowSize(f.window))
+	}
+	if f.seed != nil {
+		opts = append(opts, fastcdc.WithSeed(*f.seed))
+	}
+	switch {
+	case f.rabin && f.buzhash:
+		return nil, errors.New("-rabin and -buzhash are mutually exclusive")
+	case f.buzhash:
+		c, err := fastcdc.NewBuzhashChunker(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	case f.rabin:
+		c, err := fastcdc.NewRabinChunker(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	c, err := fastcdc.NewChunker(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseSizes returns the option of a preset name, or of chunk sizes
+// written min/avg/max.
+func parseSizes(s string) (fastcdc.Option, error) {
+	switch s {
+	case "16k":
+		return fastcdc.With16kChunks(), nil
+	case "32k":
+		return fastcdc.With32kChunks(), nil
+	case "64k", "":
+		return fastcdc.With64kChunks(), nil
+	}
+	var minSize, avgSize, maxSize uint
+	if _, err := fmt.Sscanf(s, "%d/%d/%d", &minSize, &avgSize, &maxSize); err != nil {
+		return nil, fmt.Errorf("unknown preset %q", s)
+	}
+	return fastcdc.WithChunksSize(minSize, avgSize, maxSize), nil
+}
+
+// commonOptions returns the options set by the flags, other than the
+// chunk sizes.
+func (f *chunkerFlags) commonOptions() ([]fastcdc.Option, error) {
+	var opts []fastcdc.Option
+
+	if f.bufferSize != 0 {
+		opts = append(opts, fastcdc.WithBufferSize(f.bufferSize))
+	}
+
+	algo, err := parseAlgorithm(f.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, fastcdc.WithAlgorithm(algo), fastcdc.WithNormalization(f.normalization))
+
+	if f.key != "" {
+		opts = append(opts, fastcdc.WithKey([]byte(f.key)))
+	}
+	if f.gearTable != "" {
+		b, err := os.ReadFile(f.gearTable)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 256*8 {
+			return nil, fmt.Errorf("the gear table must be %d bytes long, got %d", 256*8, len(b))
+		}
+		var t [256]uint64
+		for i := range t {
+			t[i] = binary.LittleEndian.Uint64(b[i*8:])
+		}
+		opts = append(opts, fastcdc.WithGearTable(&t))
+	}
+	if f.ronomon {
+		opts = append(opts, fastcdc.WithRonomonCompat())
+	}
+	return opts, nil
+}
+
+func parseAlgorithm(s string) (fastcdc.Algorithm, error) {
+	for _, algo := range []fastcdc.Algorithm{fastcdc.FastCDC2016, fastcdc.FastCDC2020, fastcdc.AE, fastcdc.RAM} {
+		if s == algo.String() {
+			return algo, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown algorithm %q", s)
+}
+
+// digestOption returns the option of the named digest, or nil for none.
+func digestOption(name string) (fastcdc.Option, error) {
+	switch name {
+	case "none":
+		return nil, nil
+	case "sha256":
+		return fastcdc.WithSHA256Digest(), nil
+	case "sha512_256":
+		return fastcdc.WithSHA512_256Digest(), nil
+	case "crc64":
+		return fastcdc.WithCRC64Digest(), nil
+	default:
+		return nil, fmt.Errorf("unknown digest %q", name)
+	}
+}
+
+// newFlagSet returns the flag set of the named command, with the chunker
+// flags registered unless f is nil.
+func newFlagSet(name, args string, stderr io.Writer, f *chunkerFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: fastcdc %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	if f != nil {
+		f.register(fs)
+	}
+	return fs
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+func runStats(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var cf chunkerFlags
+	fs := newFlagSet("stats", "file", stderr, &cf)
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	opts, err := cf.options()
+	if err != nil {
+		return err
+	}
+	chunker, err := cf.newSplitter(opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := open(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stats fastcdc.Stats
+	for _, err := range stats.Collect(chunker.Chunks(f)) {
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	_, err = fmt.Fprint(stdout, stats)
+	return err
+}
@@ -0,0 +1,156 @@
+// Package delta computes the difference between two versions of a chunked stream, as the ranges of the new version
+// which can be copied from the old one and the literal ranges which cannot, and encodes it as a compact patch.
+//
+// A patch starts with a magic number and the format version. Then comes one operation per range of the new version:
+// a copy is the byte 1 followed by the old offset and the length as uvarints, and a literal is the byte 2 followed by
+// the length as a uvarint and the data. The byte 0 ends the operations, followed by the big-endian CRC-32C of
+// everything before it.
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/manifest"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+var (
+	ErrConfigMismatch = errors.New("manifest of another chunker configuration")
+	ErrInvalidPatch   = errors.New("invalid patch")
+	ErrEncoderClosed  = errors.New("add on closed encoder")
+)
+
+// Kind is the kind of an operation.
+type Kind uint8
+
+const (
+	// Copy copies a range of the old version.
+	Copy Kind = iota + 1
+	// Literal inserts new data.
+	Literal
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Copy:
+		return "copy"
+	case Literal:
+		return "literal"
+	default:
+		return fmt.Sprintf("kind(%d)", uint8(k))
+	}
+}
+
+// Op is an operation rebuilding a range of the new version of a stream.
+type Op struct {
+	Kind Kind
+	// Offset is the position of the range in the new version.
+	Offset int64
+	// Length is the size of the range.
+	Length int64
+	// OldOffset is the position of the copied range in the old version.
+	// It is only set by Copy operations.
+	OldOffset int64
+	// Data is the content of the range. It is only set by the Literal
+	// operations yielded by DiffReader.
+	Data []byte
+}
+
+// Diff returns the operations which rebuild the stream described by the
+// new chunk references from the one described by the old ones. A chunk
+// of the new version found anywhere in the old one is copied, the others
+// are literals, without data. The operations are ordered and merged when
+// they are contiguous. Both versions must be chunked with the same
+// configuration for the chunks to be shared.
+func Diff(old, new []store.ChunkRef) []Op {
+	index := indexRefs(old)
+	var ops []Op
+	for _, ref := range new {
+		ops = appendRef(ops, index, ref)
+	}
+	return ops
+}
+
+// DiffReader returns an iterator over the operations which rebuild the
+// stream read from r from the old version described by m, like Diff. The
+// new version is chunked with c, which must have the configuration m was
+// built with, otherwise the iterator yields ErrConfigMismatch and stops.
+// The literal operations carry their data, owned by the caller.
+//
+// Unlike Diff, DiffReader stops merging contiguous literals once they
+// reach the max chunk size of c, so that the memory it holds does not
+// grow with the data missing from the old version.
+func DiffReader(c *fastcdc.Chunker, m *manifest.Manifest, r io.Reader) iter.Seq2[Op, error] {
+	return func(yield func(Op, error) bool) {
+		if m.Fingerprint != c.Fingerprint() {
+			yield(Op{}, ErrConfigMismatch)
+			return
+		}
+
+		_, _, maxSize := c.Sizes()
+		index := indexRefs(m.Chunks)
+		// The last operation, not yielded yet as the next chunks may
+		// extend it.
+		var pending []Op
+		for chunk, err := range c.Chunks(r) {
+			if err != nil {
+				yield(Op{}, err)
+				return
+			}
+
+			ref := store.ChunkRef{Digest: store.Sum(chunk.Data), Offset: chunk.Offset, Length: len(chunk.Data)}
+			pending = appendRef(pending, index, ref)
+			ready := len(pending) - 1
+			if last := &pending[ready]; last.Kind == Literal {
+				last.Data = append(last.Data, chunk.Data...)
+				if len(last.Data) >= int(maxSize) {
+					ready++
+				}
+			}
+			for _, op := range pending[:ready] {
+				if !yield(op, nil) {
+					return
+				}
+			}
+			pending = append(pending[:0], pending[ready:]...)
+		}
+		if len(pending) == 1 {
+			yield(pending[0], nil)
+		}
+	}
+}
+
+// indexRefs maps the digests of refs to their first chunk.
+func indexRefs(refs []store.ChunkRef) map[store.Digest]store.ChunkRef {
+	index := make(map[store.Digest]store.ChunkRef, len(refs))
+	for _, ref := range refs {
+		if _, ok := index[ref.Digest]; !ok {
+			index[ref.Digest] = ref
+		}
+	}
+	return index
+}
+
+// appendRef appends the operation rebuilding ref to ops, merging it with
+// the last operation when they are contiguous.
+func appendRef(ops []Op, index map[store.Digest]store.ChunkRef, ref store.ChunkRef) []Op {
+	op := Op{Kind: Literal, Offset: ref.Offset, Length: int64(ref.Length)}
+	if old, ok := index[ref.Digest]; ok && old.Length == ref.Length {
+		op.Kind = Copy
+		op.OldOffset = old.Offset
+	}
+
+	if n := len(ops); n > 0 {
+		last := &ops[n-1]
+		if last.Kind == op.Kind && last.Offset+last.Length == op.Offset &&
+			(op.Kind == Literal || last.OldOffset+last.Length == op.OldOffset) {
+			last.Length += op.Length
+			return ops
+		}
+	}
+	return append(ops, op)
+}
@@ -0,0 +1,316 @@
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/manifest"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+func randomData(seed uint64, size int) []byte {
+	rng := rand.New(rand.NewPCG(seed, 0))
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+	return data
+}
+
+// edit returns a copy of data with a range changed, a range inserted and
+// a range removed.
+func edit(data []byte) []byte {
+	out := slices.Clone(data[:len(data)/4])
+	out = append(out, randomData(99, 5000)...)
+	out = append(out, data[len(data)/4:len(data)/2]...)
+	changed := slices.Clone(data[len(data)/2 : len(data)*3/4])
+	for i := range 100 {
+		changed[i*100] ^= 0xff
+	}
+	out = append(out, changed...)
+	return append(out, data[len(data)*3/4+20_000:]...)
+}
+
+func refsOf(t *testing.T, chunker *fastcdc.Chunker, data []byte) []store.ChunkRef {
+	t.Helper()
+	refs, err := store.StoreStream(chunker, bytes.NewReader(data), store.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refs
+}
+
+// checkOps checks that the operations cover the new version in order and
+// rebuild it from the old one.
+func checkOps(t *testing.T, ops []Op, oldData, newData []byte) {
+	t.Helper()
+	var offset int64
+	for _, op := range ops {
+		if op.Offset != offset || op.Length <= 0 {
+			t.Fatalf("%s of %d bytes at offset %d, want offset %d", op.Kind, op.Length, op.Offset, offset)
+		}
+		want := newData[op.Offset : op.Offset+op.Length]
+		switch op.Kind {
+		case Copy:
+			if !bytes.Equal(oldData[op.OldOffset:op.OldOffset+op.Length], want) {
+				t.Fatalf("copy at offset %d: content mismatch", op.Offset)
+			}
+		case Literal:
+			if op.Data != nil && !bytes.Equal(op.Data, want) {
+				t.Fatalf("literal at offset %d: content mismatch", op.Offset)
+			}
+		}
+		offset += op.Length
+	}
+	if offset != int64(len(newData)) {
+		t.Fatalf("size: want = %d, got = %d", len(newData), offset)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldData := randomData(1, 4<<20)
+	newData := edit(oldData)
+
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := Diff(refsOf(t, chunker, oldData), refsOf(t, chunker, newData))
+	checkOps(t, ops, oldData, newData)
+
+	var copied int64
+	for i, op := range ops {
+		if op.Kind == Copy {
+			copied += op.Length
+		}
+		if i > 0 && op.Kind == Literal && ops[i-1].Kind == Literal {
+			t.Errorf("contiguous literals at offset %d are not merged", op.Offset)
+		}
+	}
+	if copied < int64(len(newData))*9/10 {
+		t.Errorf("copied bytes: want > 90%% of %d, got = %d", len(newData), copied)
+	}
+
+	if ops := Diff(nil, nil); len(ops) != 0 {
+		t.Errorf("empty streams: want no operation, got = %v", ops)
+	}
+}
+
+func TestDiffReaderApply(t *testing.T) {
+	oldData := randomData(2, 4<<20)
+	newData := edit(oldData)
+
+	for _, algo := range []fastcdc.Algorithm{fastcdc.FastCDC2016, fastcdc.FastCDC2020} {
+		t.Run(algo.String(), func(t *testing.T) {
+			chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks(), fastcdc.WithAlgorithm(algo))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := manifest.New(chunker, refsOf(t, chunker, oldData))
+
+			var ops []Op
+			var patch bytes.Buffer
+			enc := NewEncoder(&patch)
+			for op, err := range DiffReader(chunker, m, iotest.HalfReader(bytes.NewReader(newData))) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if op.Kind == Literal && int64(len(op.Data)) != op.Length {
+					t.Fatalf("literal at offset %d: want %d bytes of data, got = %d", op.Offset, op.Length, len(op.Data))
+				}
+				ops = append(ops, op)
+				if err := enc.Add(op); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			checkOps(t, ops, oldData, newData)
+
+			// The same operations as Diff, with data.
+			want := Diff(m.Chunks, refsOf(t, chunker, newData))
+			if len(ops) != len(want) {
+				t.Fatalf("operations: want = %d, got = %d", len(want), len(ops))
+			}
+			for i := range want {
+				got := ops[i]
+				if got.Kind != want[i].Kind || got.Offset != want[i].Offset || got.Length != want[i].Length || got.OldOffset != want[i].OldOffset {
+					t.Errorf("operation %d: want = %v, got = %v", i, want[i], got)
+				}
+			}
+
+			if patch.Len() > len(newData)/5 {
+				t.Errorf("patch size: want < %d, got = %d", len(newData)/5, patch.Len())
+			}
+
+			var got bytes.Buffer
+			if err := Apply(&got, bytes.NewReader(oldData), iotest.OneByteReader(bytes.NewReader(patch.Bytes()))); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), newData) {
+				t.Error("the patched stream does not match the new version")
+			}
+		})
+	}
+}
+
+// randomReader delivers n pseudo-random bytes without holding them.
+type randomReader struct {
+	rng *rand.Rand
+	n   int
+}
+
+func (r *randomReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	p = p[:min(len(p), r.n)]
+	for i := range p {
+		p[i] = byte(r.rng.Uint32())
+	}
+	r.n -= len(p)
+	return len(p), nil
+}
+
+// TestDiffReaderBoundedLiterals checks that DiffReader does not hold the
+// new data which has nothing in common with the old version.
+func TestDiffReaderBoundedLiterals(t *testing.T) {
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, maxSize := chunker.Sizes()
+	m := manifest.New(chunker, refsOf(t, chunker, randomData(4, 1<<20)))
+
+	const size = 64 << 20
+	var (
+		ms       runtime.MemStats
+		offset   int64
+		peakHeap uint64
+	)
+	r := &randomReader{rng: rand.New(rand.NewPCG(5, 0)), n: size}
+	for op, err := range DiffReader(chunker, m, r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op.Kind != Literal || op.Offset != offset || int64(len(op.Data)) != op.Length {
+			t.Fatalf("want a literal at offset %d with its data, got = %s of %d bytes at offset %d with %d bytes of data", offset, op.Kind, op.Length, op.Offset, len(op.Data))
+		}
+		if op.Length >= 2*int64(maxSize) {
+			t.Fatalf("literal at offset %d: want < %d bytes, got = %d", op.Offset, 2*maxSize, op.Length)
+		}
+		offset += op.Length
+		if offset/(4<<20) != (offset-op.Length)/(4<<20) {
+			runtime.GC()
+			runtime.ReadMemStats(&ms)
+			peakHeap = max(peakHeap, ms.HeapAlloc)
+		}
+	}
+	if offset != size {
+		t.Fatalf("size: want = %d, got = %d", size, offset)
+	}
+	if peakHeap > 16<<20 {
+		t.Errorf("heap: want < %d, got = %d", 16<<20, peakHeap)
+	}
+}
+
+func TestDiffReaderConfigMismatch(t *testing.T) {
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := fastcdc.NewChunker(fastcdc.With32kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest.New(chunker, nil)
+	var gotErr error
+	for _, err := range DiffReader(other, m, bytes.NewReader(randomData(3, 1000))) {
+		gotErr = err
+	}
+	if !errors.Is(gotErr, ErrConfigMismatch) {
+		t.Errorf("want = %s, got = %s", ErrConfigMismatch, gotErr)
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	enc := NewEncoder(io.Discard)
+	tests := map[string]Op{
+		"gap":          {Kind: Copy, Offset: 10, Length: 10},
+		"empty":        {Kind: Copy, Length: 0},
+		"missing data": {Kind: Literal, Length: 10},
+		"unknown kind": {Kind: 42, Length: 10},
+	}
+	for name, op := range tests {
+		if err := enc.Add(op); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("%s: want = %s, got = %s", name, ErrInvalidPatch, err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Add(Op{Kind: Copy, Length: 10}); !errors.Is(err, ErrEncoderClosed) {
+		t.Errorf("want = %s, got = %s", ErrEncoderClosed, err)
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	oldData := randomData(4, 1000)
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, op := range []Op{
+		{Kind: Copy, Offset: 0, Length: 500, OldOffset: 200},
+		{Kind: Literal, Offset: 500, Length: 3, Data: []byte("foo")},
+	} {
+		if err := enc.Add(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	var got bytes.Buffer
+	if err := Apply(&got, bytes.NewReader(oldData), bytes.NewReader(valid)); err != nil {
+		t.Fatal(err)
+	}
+	if want := append(slices.Clone(oldData[200:700]), "foo"...); !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("want = %x, got = %x", want, got.Bytes())
+	}
+
+	badMagic := slices.Clone(valid)
+	badMagic[0] = 'X'
+	flipped := slices.Clone(valid)
+	flipped[len(valid)-7] ^= 1
+
+	tests := map[string]struct {
+		Old   []byte
+		Patch []byte
+	}{
+		"empty":     {oldData, nil},
+		"bad magic": {oldData, badMagic},
+		"truncated": {oldData, valid[:len(valid)-2]},
+		"corrupted": {oldData, flipped},
+		"short old": {oldData[:600], valid},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Apply(io.Discard, bytes.NewReader(tc.Old), bytes.NewReader(tc.Patch))
+			if !errors.Is(err, ErrInvalidPatch) {
+				t.Errorf("want = %s, got = %s", ErrInvalidPatch, err)
+			}
+		})
+	}
+}
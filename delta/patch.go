@@ -0,0 +1,180 @@
+package delta
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2/internal/crcio"
+)
+
+// Version is the version of the patch format written by this package.
+const Version = 1
+
+var magic = [7]byte{'F', 'C', 'D', 'E', 'L', 'T', 'A'}
+
+const (
+	tagEnd byte = iota
+	tagCopy
+	tagLiteral
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Encoder writes a patch incrementally, as the operations are yielded by
+// DiffReader.
+type Encoder struct {
+	w      *bufio.Writer
+	crc    hash.Hash32
+	offset int64 // position of the next range in the new version
+	buf    []byte
+	err    error
+	closed bool
+}
+
+// NewEncoder returns an encoder writing a patch to w. Close must be
+// called to complete the patch.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{w: bufio.NewWriter(w), crc: crc32.New(castagnoli)}
+	e.write(append(magic[:], Version))
+	return e
+}
+
+// Add appends the operation to the patch. The operations must be added in
+// order, starting at offset 0, and the literals must carry their data.
+func (e *Encoder) Add(op Op) error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	if e.err != nil {
+		return e.err
+	}
+	if op.Offset != e.offset || op.Length <= 0 {
+		return fmt.Errorf("%s of %d bytes at offset %d, want offset %d: %w", op.Kind, op.Length, op.Offset, e.offset, ErrInvalidPatch)
+	}
+
+	switch op.Kind {
+	case Copy:
+		if op.OldOffset < 0 {
+			return fmt.Errorf("copy from negative offset %d: %w", op.OldOffset, ErrInvalidPatch)
+		}
+		e.buf = append(e.buf[:0], tagCopy)
+		e.buf = binary.AppendUvarint(e.buf, uint64(op.OldOffset))
+		e.buf = binary.AppendUvarint(e.buf, uint64(op.Length))
+		e.write(e.buf)
+	case Literal:
+		if int64(len(op.Data)) != op.Length {
+			return fmt.Errorf("literal of %d bytes with %d bytes of data: %w", op.Length, len(op.Data), ErrInvalidPatch)
+		}
+		e.buf = append(e.buf[:0], tagLiteral)
+		e.buf = binary.AppendUvarint(e.buf, uint64(op.Length))
+		e.write(e.buf)
+		e.write(op.Data)
+	default:
+		return fmt.Errorf("unknown operation %s: %w", op.Kind, ErrInvalidPatch)
+	}
+	e.offset += op.Length
+	return e.err
+}
+
+// Close writes the end of the patch and flushes it. It does not close the
+// underlying writer. Close is idempotent.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+
+	e.write([]byte{tagEnd})
+	e.write(binary.BigEndian.AppendUint32(nil, e.crc.Sum32()))
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *Encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, _ = e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+// Apply rebuilds the new version of a stream from its old version and the
+// patch read from r, and writes it to w. The old version can be read back
+// from its manifest with manifest.NewReassembler. The patch checksum is
+// only verified at the end: on error, what was written to w must be
+// discarded. Apply returns ErrInvalidPatch if the patch is malformed or
+// corrupted, or if it copies past the end of the old version.
+func Apply(w io.Writer, old io.ReaderAt, r io.Reader) error {
+	pr := crcio.NewReader(r, ErrInvalidPatch)
+
+	var prefix [len(magic) + 1]byte
+	if _, err := io.ReadFull(pr, prefix[:]); err != nil {
+		return pr.Unexpected(err)
+	}
+	if [len(magic)]byte(prefix[:len(magic)]) != magic {
+		return fmt.Errorf("bad magic number: %w", ErrInvalidPatch)
+	}
+	if v := prefix[len(magic)]; v != Version {
+		return fmt.Errorf("unsupported patch version %d: %w", v, ErrInvalidPatch)
+	}
+
+	for {
+		tag, err := pr.ReadByte()
+		if err != nil {
+			return pr.Unexpected(err)
+		}
+		switch tag {
+		case tagEnd:
+			sum := pr.Sum32()
+			var b [4]byte
+			if _, err := io.ReadFull(pr, b[:]); err != nil {
+				return pr.Unexpected(err)
+			}
+			if binary.BigEndian.Uint32(b[:]) != sum {
+				return fmt.Errorf("checksum mismatch: %w", ErrInvalidPatch)
+			}
+			return nil
+		case tagCopy:
+			offset, err := binary.ReadUvarint(pr)
+			if err != nil {
+				return pr.Unexpected(err)
+			}
+			length, err := binary.ReadUvarint(pr)
+			if err != nil {
+				return pr.Unexpected(err)
+			}
+			if offset > 1<<62 || length > 1<<62 {
+				return fmt.Errorf("copy of %d bytes at offset %d: %w", length, offset, ErrInvalidPatch)
+			}
+			n, err := io.Copy(w, io.NewSectionReader(old, int64(offset), int64(length)))
+			if err != nil {
+				return err
+			}
+			if n != int64(length) {
+				return fmt.Errorf("copy of %d bytes at offset %d past the end of the old version: %w", length, offset, ErrInvalidPatch)
+			}
+		case tagLiteral:
+			length, err := binary.ReadUvarint(pr)
+			if err != nil {
+				return pr.Unexpected(err)
+			}
+			if length > 1<<62 {
+				return fmt.Errorf("literal of %d bytes: %w", length, ErrInvalidPatch)
+			}
+			if _, err := io.CopyN(w, pr, int64(length)); err != nil {
+				if err == io.EOF {
+					return pr.Unexpected(err)
+				}
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown operation %d: %w", tag, ErrInvalidPatch)
+		}
+	}
+}
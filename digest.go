@@ -0,0 +1,33 @@
+package fastcdc
+
+import "hash"
+
+// digester computes the digests of the chunks of a single iteration. The
+// returned sum is reused from one chunk to the next, so computing a
+// digest does not allocate.
+type digester struct {
+	h   hash.Hash
+	sum []byte
+}
+
+// newDigester returns a digester for the chunker digest configuration.
+// Without digest, the digester returns nil sums.
+func (c *Chunker) newDigester() digester {
+	if c.newHash == nil {
+		return digester{}
+	}
+	h := c.newHash()
+	return digester{h: h, sum: make([]byte, 0, h.Size())}
+}
+
+// digest returns the digest of data. It is only valid until the next
+// call.
+func (d *digester) digest(data []byte) []byte {
+	if d.h == nil {
+		return nil
+	}
+	d.h.Reset()
+	_, _ = d.h.Write(data)
+	d.sum = d.h.Sum(d.sum[:0])
+	return d.sum
+}
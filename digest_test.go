@@ -0,0 +1,116 @@
+package fastcdc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"hash/crc64"
+	"testing"
+)
+
+var digestPresets = map[string]struct {
+	Opt     Option
+	NewHash func() hash.Hash
+}{
+	"sha256":     {WithSHA256Digest(), sha256.New},
+	"sha512/256": {WithSHA512_256Digest(), sha512.New512_256},
+	"crc64":      {WithCRC64Digest(), func() hash.Hash { return crc64.New(crc64.MakeTable(crc64.ECMA)) }},
+}
+
+func checkSum(t *testing.T, newHash func() hash.Hash, chunk Chunk) {
+	t.Helper()
+	h := newHash()
+	h.Write(chunk.Data)
+	if want := h.Sum(nil); !bytes.Equal(chunk.Sum, want) {
+		t.Fatalf("sum at offset %d: want = %x, got = %x", chunk.Offset, want, chunk.Sum)
+	}
+}
+
+func TestChunkDigest(t *testing.T) {
+	data := randomData(21, 2<<20)
+
+	for name, tc := range digestPresets {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(With16kChunks(), tc.Opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSum(t, tc.NewHash, chunk)
+			}
+
+			for chunk := range chunker.ChunkBytes(data) {
+				checkSum(t, tc.NewHash, chunk)
+			}
+
+			for chunk, err := range chunker.chunkReaderAt(bytes.NewReader(data), int64(len(data)), 4, 100_000) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSum(t, tc.NewHash, chunk)
+			}
+
+			w := chunker.NewWriter(func(chunk Chunk) error {
+				checkSum(t, tc.NewHash, chunk)
+				return nil
+			})
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNoDigest(t *testing.T) {
+	data := sekienData(t)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Sum != nil {
+			t.Errorf("sum: want = nil, got = %x", chunk.Sum)
+		}
+	}
+}
+
+func TestChunksDigestAllocs(t *testing.T) {
+	data := randomData(7, 4<<20)
+
+	for name, tc := range digestPresets {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(With64kChunks(), tc.Opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			reader := bytes.NewReader(data)
+			avg := testing.AllocsPerRun(5, func() {
+				reader.Reset(data)
+				for _, err := range chunker.Chunks(reader) {
+					if err != nil {
+						panic(err)
+					}
+				}
+			})
+
+			// Computing the digests must not allocate per chunk.
+			if avg > 4 {
+				t.Errorf("allocs per run: want <= 4, got = %g", avg)
+			}
+		})
+	}
+}
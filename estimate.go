@@ -0,0 +1,116 @@
+package fastcdc
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+// Estimation is the deduplication estimated for a configuration of the
+// chunker over a corpus.
+type Estimation struct {
+	// MinSize, AvgSize and MaxSize are the chunk sizes of the
+	// configuration.
+	MinSize uint
+	AvgSize uint
+	MaxSize uint
+	// Files is the number of files of the corpus.
+	Files int
+	// Chunks and Bytes are the number and the total size of the chunks.
+	Chunks int64
+	Bytes  int64
+	// UniqueChunks and UniqueBytes are the number and the total size of
+	// the distinct chunks, which a deduplicating store would hold.
+	UniqueChunks int64
+	UniqueBytes  int64
+}
+
+// DedupRatio returns the ratio of the logical bytes to the unique bytes,
+// 1 for a corpus without duplicate chunks. It returns 0 for an empty
+// corpus.
+func (e Estimation) DedupRatio() float64 {
+	if e.UniqueBytes == 0 {
+		return 0
+	}
+	return float64(e.Bytes) / float64(e.UniqueBytes)
+}
+
+// Estimate estimates the deduplication each configuration of the chunker
+// would give over the regular files of fsys, walked from its root. The
+// distinct chunks are tracked in memory by their SHA-256 digest. The
+// configurations are evaluated concurrently, and the estimations are
+// returned in the same order. Without configuration, Estimate evaluates
+// the default configuration.
+//
+//	estimations, err := fastcdc.Estimate(os.DirFS(dir),
+//		[]fastcdc.Option{fastcdc.With16kChunks()},
+//		[]fastcdc.Option{fastcdc.With32kChunks()},
+//		[]fastcdc.Option{fastcdc.With64kChunks()},
+//	)
+func Estimate(fsys fs.FS, configs ...[]Option) ([]Estimation, error) {
+	if len(configs) == 0 {
+		configs = [][]Option{nil}
+	}
+
+	chunkers := make([]*Chunker, len(configs))
+	for i, opts := range configs {
+		c, err := NewChunker(opts...)
+		if err != nil {
+			return nil, err
+		}
+		chunkers[i] = c
+	}
+
+	estimations := make([]Estimation, len(chunkers))
+	errs := make([]error, len(chunkers))
+	var wg sync.WaitGroup
+	for i, c := range chunkers {
+		wg.Go(func() {
+			estimations[i], errs[i] = c.estimate(fsys)
+		})
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return estimations, nil
+}
+
+// estimate walks fsys and chunks its regular files.
+func (c *Chunker) estimate(fsys fs.FS) (Estimation, error) {
+	e := Estimation{MinSize: c.minSize, AvgSize: c.avgSize, MaxSize: c.maxSize}
+	seen := make(map[[sha256.Size]byte]struct{})
+
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		e.Files++
+		for chunk, err := range c.Chunks(f) {
+			if err != nil {
+				return err
+			}
+			n := int64(len(chunk.Data))
+			e.Chunks++
+			e.Bytes += n
+
+			sum := sha256.Sum256(chunk.Data)
+			if _, ok := seen[sum]; !ok {
+				seen[sum] = struct{}{}
+				e.UniqueChunks++
+				e.UniqueBytes += n
+			}
+		}
+		return nil
+	})
+	return e, err
+}
@@ -0,0 +1,74 @@
+package fastcdc
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEstimate(t *testing.T) {
+	base := randomData(41, 4<<20)
+	edited := slices.Concat(base[:1<<20], randomData(42, 1000), base[1<<20:])
+
+	fsys := fstest.MapFS{
+		"base":         {Data: base},
+		"dir/copy":     {Data: base},
+		"dir/edited":   {Data: edited},
+		"dir/sub/zero": {Data: make([]byte, 1<<20)},
+		"empty":        {Data: nil},
+	}
+	logical := int64(len(base)*2 + len(edited) + 1<<20)
+
+	estimations, err := Estimate(fsys, []Option{With16kChunks()}, []Option{With64kChunks()}, []Option{With64kChunks(), WithAlgorithm(FastCDC2020)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimations) != 3 {
+		t.Fatalf("estimations: want = 3, got = %d", len(estimations))
+	}
+	if estimations[0].AvgSize != 16_384 || estimations[1].AvgSize != 65_536 {
+		t.Errorf("avg size: want = 16384 65536, got = %d %d", estimations[0].AvgSize, estimations[1].AvgSize)
+	}
+
+	for i, e := range estimations {
+		if e.Files != 5 || e.Bytes != logical {
+			t.Errorf("config %d: files and bytes: want = 5 %d, got = %d %d", i, logical, e.Files, e.Bytes)
+		}
+		// The copy and most of the edited file are duplicates, and the
+		// zero file is made of identical chunks.
+		if e.UniqueBytes < int64(len(base)) || e.UniqueBytes > int64(len(base))+1<<20 {
+			t.Errorf("config %d: unique bytes: want ~%d, got = %d", i, len(base), e.UniqueBytes)
+		}
+		if r := e.DedupRatio(); r < 2.5 || r > 3.3 {
+			t.Errorf("config %d: dedup ratio: want ~3, got = %f", i, r)
+		}
+		if e.UniqueChunks >= e.Chunks {
+			t.Errorf("config %d: unique chunks: want < %d, got = %d", i, e.Chunks, e.UniqueChunks)
+		}
+	}
+	// Smaller chunks isolate the edit better.
+	if estimations[0].UniqueBytes >= estimations[1].UniqueBytes {
+		t.Errorf("unique bytes: want 16k < 64k, got = %d >= %d", estimations[0].UniqueBytes, estimations[1].UniqueBytes)
+	}
+}
+
+func TestEstimateDefault(t *testing.T) {
+	estimations, err := Estimate(fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimations) != 1 || estimations[0].AvgSize != 65_536 || estimations[0].Files != 0 {
+		t.Errorf("want one empty estimation of the default configuration, got = %v", estimations)
+	}
+	if r := estimations[0].DedupRatio(); r != 0 {
+		t.Errorf("dedup ratio: want = 0, got = %f", r)
+	}
+}
+
+func TestEstimateInvalidConfig(t *testing.T) {
+	_, err := Estimate(fstest.MapFS{}, []Option{With16kChunks()}, []Option{WithChunksSize(1, 2, 3)})
+	if !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("want = %s, got = %s", ErrInvalidChunkSize, err)
+	}
+}
@@ -0,0 +1,85 @@
+package fastcdc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// aeValueSize is the size of the values compared by AE. Bytes would
+// saturate at 255 right after the start of the search, so AE compares the
+// 8-byte values ending at every position, which are almost always
+// distinct.
+const aeValueSize = 8
+
+// initExtremum sets up the window of the AE and RAM algorithms, which
+// derives from the chunk sizes.
+func (c *Chunker) initExtremum(config *config) error {
+	if config.key != nil || config.table != nil {
+		return fmt.Errorf("%s does not use a gear table: %w", config.algorithm, ErrInvalidGearTable)
+	}
+	if config.normalization != 1 {
+		return fmt.Errorf("%s has no normalized chunking: %w", config.algorithm, ErrInvalidNormalization)
+	}
+	if config.window != 0 || config.polynomial != 0 || config.seed != nil {
+		return fmt.Errorf("the window size, the polynomial and the seed only apply to the Rabin and buzhash chunkers: %w", ErrInvalidAlgorithm)
+	}
+
+	if config.algorithm == AE {
+		// With distinct values, the expected distance from the search start
+		// to the cut point is (e - 1) times the window.
+		c.window = max(uint(math.Round(float64(config.avgSize-config.minSize)/(math.E-1))), 1)
+		c.skip = config.minSize - (aeValueSize - 1)
+		return nil
+	}
+	// The maximum of a large window of bytes is almost always 255, so the
+	// cut point usually follows the window by a few hundred bytes.
+	c.window = config.avgSize - config.minSize
+	return nil
+}
+
+// searchAE is the cut point search of the AE algorithm. The search starts
+// at the min size: a position is a cut point when its value is the
+// maximum since the search start and no later value exceeds it for
+// window bytes, which is the asymmetric extremum of the paper.
+func (c *Chunker) searchAE(window []byte) (uint, CutReason) {
+	length := uint(len(window))
+
+	// The value of a position is the 8 bytes ending at it, so the values
+	// never look past the cut point.
+	value := func(i uint) uint64 {
+		return binary.BigEndian.Uint64(window[i+1-aeValueSize : i+1])
+	}
+
+	maxPos := c.minSize
+	maxValue := value(maxPos)
+	for i := maxPos + 1; i < length; i++ {
+		if v := value(i); v > maxValue {
+			maxValue, maxPos = v, i
+			continue
+		}
+		if i == maxPos+c.window {
+			return i + 1, CutContent
+		}
+	}
+	return 0, CutNone
+}
+
+// searchRAM is the cut point search of the RAM algorithm. The maximum
+// byte of the window which follows the min size is the threshold, and the
+// chunk is cut at the first byte after the window which reaches it.
+func (c *Chunker) searchRAM(window []byte) (uint, CutReason) {
+	length := uint(len(window))
+
+	end := min(c.minSize+c.window, length)
+	var threshold byte
+	for _, b := range window[c.minSize:end] {
+		threshold = max(threshold, b)
+	}
+	for i := end; i < length; i++ {
+		if window[i] >= threshold {
+			return i + 1, CutContent
+		}
+	}
+	return 0, CutNone
+}
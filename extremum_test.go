@@ -0,0 +1,96 @@
+package fastcdc
+
+import (
+	"errors"
+	"testing"
+)
+
+var sekienAEGoldens = map[string]sekienGolden{
+	"16kChunks": {
+		Preset:  With16kChunks(),
+		MaxSize: 131_072,
+		Want: []chunkInfo{
+			{0, 13023}, {13023, 11416}, {24439, 14558}, {38997, 15297},
+			{54294, 17014}, {71308, 17892}, {89200, 14098}, {103298, 6168},
+		},
+	},
+	"32kChunks": {
+		Preset:  With32kChunks(),
+		MaxSize: 262_144,
+		Want:    []chunkInfo{{0, 31275}, {31275, 78191}},
+	},
+}
+
+var sekienRAMGoldens = map[string]sekienGolden{
+	"16kChunks": {
+		Preset:  With16kChunks(),
+		MaxSize: 131_072,
+		Want: []chunkInfo{
+			{0, 16965}, {16965, 16415}, {33380, 16426}, {49806, 16483},
+			{66289, 16444}, {82733, 16402}, {99135, 10331},
+		},
+	},
+	"32kChunks": {
+		Preset:  With32kChunks(),
+		MaxSize: 262_144,
+		Want:    []chunkInfo{{0, 32793}, {32793, 32800}, {65593, 32862}, {98455, 11011}},
+	},
+}
+
+// TestExtremumDistribution checks that AE and RAM keep the expected
+// average chunk size, with a lower variance than FastCDC2016.
+func TestExtremumDistribution(t *testing.T) {
+	data := randomData(25, 32<<20)
+
+	stats := func(algo Algorithm) Stats {
+		chunker, err := NewChunker(With16kChunks(), WithAlgorithm(algo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Stats
+		for chunk := range chunker.ChunkBytes(data) {
+			s.Add(chunk)
+		}
+		return s
+	}
+
+	reference := stats(FastCDC2016)
+	for _, algo := range []Algorithm{AE, RAM} {
+		s := stats(algo)
+		if mean := s.Mean(); mean < 16_384*0.9 || mean > 16_384*1.1 {
+			t.Errorf("%s: mean: want = 16384 ± 10%%, got = %.0f", algo, mean)
+		}
+		if s.StdDev() >= reference.StdDev() {
+			t.Errorf("%s: stddev: want < %.0f, got = %.0f", algo, reference.StdDev(), s.StdDev())
+		}
+		if s.Min < 4096 || s.Max > 131_072 {
+			t.Errorf("%s: chunk size out of bounds: [%d, %d]", algo, s.Min, s.Max)
+		}
+	}
+}
+
+func TestExtremumValidation(t *testing.T) {
+	cases := map[string]struct {
+		Opts []Option
+		Err  error
+	}{
+		"default":       {nil, nil},
+		"small sizes":   {[]Option{WithChunksSize(64, 256, 1024)}, nil},
+		"key":           {[]Option{WithKey([]byte("secret"))}, ErrInvalidGearTable},
+		"gear table":    {[]Option{WithGearTable(&table)}, ErrInvalidGearTable},
+		"normalization": {[]Option{WithNormalization(2)}, ErrInvalidNormalization},
+		"window":        {[]Option{WithWindowSize(64)}, ErrInvalidAlgorithm},
+		"seed":          {[]Option{WithSeed(1)}, ErrInvalidAlgorithm},
+		"ronomon":       {[]Option{WithRonomonCompat()}, ErrInvalidAlgorithm},
+	}
+	for _, algo := range []Algorithm{AE, RAM} {
+		for name, tc := range cases {
+			t.Run(algo.String()+"/"+name, func(t *testing.T) {
+				_, err := NewChunker(append(tc.Opts, WithAlgorithm(algo))...)
+				if !errors.Is(err, tc.Err) {
+					t.Errorf("error: want = %v, got = %v", tc.Err, err)
+				}
+			})
+		}
+	}
+}
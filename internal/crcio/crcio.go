@@ -0,0 +1,72 @@
+// Package crcio reads the binary formats of the manifest and delta
+// packages, which end with the CRC-32C of their content.
+package crcio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Reader computes the checksum of the bytes read, and records the error
+// of the underlying reader.
+type Reader struct {
+	r       *bufio.Reader
+	crc     hash.Hash32
+	invalid error
+	err     error
+	b       [1]byte
+}
+
+// NewReader returns a reader of r. invalid is the error of a malformed
+// content, returned by Unexpected.
+func NewReader(r io.Reader, invalid error) *Reader {
+	return &Reader{r: bufio.NewReader(r), crc: crc32.New(castagnoli), invalid: invalid}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	_, _ = r.crc.Write(p[:n])
+	r.record(err)
+	return n, err
+}
+
+func (r *Reader) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err != nil {
+		r.record(err)
+		return 0, err
+	}
+	r.b[0] = c
+	_, _ = r.crc.Write(r.b[:])
+	return c, nil
+}
+
+// Sum32 returns the checksum of the bytes read so far.
+func (r *Reader) Sum32() uint32 {
+	return r.crc.Sum32()
+}
+
+// Unexpected returns the error of the underlying reader as is, and turns
+// the others, like a truncated content or an overflowing varint, into
+// the invalid error of the reader.
+func (r *Reader) Unexpected(err error) error {
+	if r.err != nil {
+		return r.err
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: %w", r.invalid, err)
+}
+
+func (r *Reader) record(err error) {
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,253 @@
+// Package manifest implements a versioned binary format describing a chunked stream as the ordered list of its chunks,
+// and a reader which reassembles the stream from a chunk store.
+//
+// A manifest starts with a magic number and the format version, followed by the fingerprint and the chunk sizes of the
+// chunker configuration. Then comes one entry per chunk, with the chunk length as a uvarint and the SHA-256 digest of
+// the chunk. The chunk offsets are implied by the lengths. A zero length ends the entries, followed by the number of
+// chunks as a uvarint and the big-endian CRC-32C of everything before it.
+package manifest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/internal/crcio"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+// Version is the version of the manifest format written by this package.
+const Version = 1
+
+var magic = [7]byte{'F', 'A', 'S', 'T', 'C', 'D', 'C'}
+
+var (
+	ErrInvalidManifest = errors.New("invalid manifest")
+	ErrEncoderClosed   = errors.New("add on closed encoder")
+	ErrCorruptChunk    = errors.New("chunk does not match the manifest")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Header describes the chunker configuration of a manifest.
+type Header struct {
+	// Fingerprint identifies the chunker configuration. See
+	// fastcdc.Chunker.Fingerprint.
+	Fingerprint uint64
+	// MinSize, AvgSize and MaxSize are the chunk sizes of the chunker.
+	MinSize uint
+	AvgSize uint
+	MaxSize uint
+}
+
+// HeaderOf returns the header of the manifests of the streams chunked
+// with c.
+func HeaderOf(c *fastcdc.Chunker) Header {
+	minSize, avgSize, maxSize := c.Sizes()
+	return Header{
+		Fingerprint: c.Fingerprint(),
+		MinSize:     minSize,
+		AvgSize:     avgSize,
+		MaxSize:     maxSize,
+	}
+}
+
+// Manifest is the description of a chunked stream.
+type Manifest struct {
+	Header
+	// Chunks are the chunk references of the stream, in order.
+	Chunks []store.ChunkRef
+}
+
+// New returns the manifest of a stream chunked with c, for example by
+// store.StoreStream.
+func New(c *fastcdc.Chunker, refs []store.ChunkRef) *Manifest {
+	return &Manifest{Header: HeaderOf(c), Chunks: refs}
+}
+
+// Size returns the size of the stream.
+func (m *Manifest) Size() int64 {
+	if len(m.Chunks) == 0 {
+		return 0
+	}
+	last := m.Chunks[len(m.Chunks)-1]
+	return last.Offset + int64(last.Length)
+}
+
+// WriteTo encodes the manifest to w. It implements io.WriterTo.
+func (m *Manifest) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := newEncoder(cw, m.Header)
+	for _, ref := range m.Chunks {
+		if err := enc.AddRef(ref); err != nil {
+			return cw.n, err
+		}
+	}
+	err := enc.Close()
+	return cw.n, err
+}
+
+// Encoder writes a manifest incrementally, as the chunks of the stream
+// are yielded by the chunker.
+type Encoder struct {
+	w      *bufio.Writer
+	crc    hash.Hash32
+	offset int64 // stream position of the next chunk
+	count  uint64
+	buf    []byte
+	err    error
+	closed bool
+}
+
+// NewEncoder returns an encoder writing to w the manifest of a stream
+// chunked with c. Close must be called to complete the manifest.
+func NewEncoder(w io.Writer, c *fastcdc.Chunker) *Encoder {
+	return newEncoder(w, HeaderOf(c))
+}
+
+func newEncoder(w io.Writer, h Header) *Encoder {
+	e := &Encoder{w: bufio.NewWriter(w), crc: crc32.New(castagnoli)}
+
+	b := append(magic[:], Version)
+	b = binary.BigEndian.AppendUint64(b, h.Fingerprint)
+	b = binary.AppendUvarint(b, uint64(h.MinSize))
+	b = binary.AppendUvarint(b, uint64(h.AvgSize))
+	b = binary.AppendUvarint(b, uint64(h.MaxSize))
+	e.write(b)
+	return e
+}
+
+// Add appends the chunk to the manifest. The chunks must be added in
+// order, starting at offset 0.
+func (e *Encoder) Add(chunk fastcdc.Chunk) error {
+	return e.AddRef(store.ChunkRef{Digest: store.Sum(chunk.Data), Offset: chunk.Offset, Length: len(chunk.Data)})
+}
+
+// AddRef appends the chunk reference to the manifest. The references
+// must be added in order, starting at offset 0.
+func (e *Encoder) AddRef(ref store.ChunkRef) error {
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	if e.err != nil {
+		return e.err
+	}
+	if ref.Offset != e.offset {
+		return fmt.Errorf("chunk at offset %d, want %d: %w", ref.Offset, e.offset, ErrInvalidManifest)
+	}
+	if ref.Length <= 0 {
+		return fmt.Errorf("empty chunk at offset %d: %w", ref.Offset, ErrInvalidManifest)
+	}
+
+	e.buf = binary.AppendUvarint(e.buf[:0], uint64(ref.Length))
+	e.buf = append(e.buf, ref.Digest[:]...)
+	e.write(e.buf)
+	e.offset += int64(ref.Length)
+	e.count++
+	return e.err
+}
+
+// Close writes the end of the manifest and flushes it. It does not close
+// the underlying writer. Close is idempotent.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+
+	e.write(binary.AppendUvarint([]byte{0}, e.count))
+	e.write(binary.BigEndian.AppendUint32(nil, e.crc.Sum32()))
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *Encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, _ = e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+// Decode reads a manifest from r. It returns ErrInvalidManifest if the
+// manifest is malformed or corrupted.
+func Decode(r io.Reader) (*Manifest, error) {
+	cr := crcio.NewReader(r, ErrInvalidManifest)
+
+	var prefix [len(magic) + 1 + 8]byte
+	if _, err := io.ReadFull(cr, prefix[:]); err != nil {
+		return nil, cr.Unexpected(err)
+	}
+	if [len(magic)]byte(prefix[:len(magic)]) != magic {
+		return nil, fmt.Errorf("bad magic number: %w", ErrInvalidManifest)
+	}
+	if v := prefix[len(magic)]; v != Version {
+		return nil, fmt.Errorf("unsupported manifest version %d: %w", v, ErrInvalidManifest)
+	}
+
+	m := &Manifest{}
+	m.Fingerprint = binary.BigEndian.Uint64(prefix[len(magic)+1:])
+	for _, size := range []*uint{&m.MinSize, &m.AvgSize, &m.MaxSize} {
+		v, err := binary.ReadUvarint(cr)
+		if err != nil {
+			return nil, cr.Unexpected(err)
+		}
+		*size = uint(v)
+	}
+
+	var offset int64
+	for {
+		length, err := binary.ReadUvarint(cr)
+		if err != nil {
+			return nil, cr.Unexpected(err)
+		}
+		if length == 0 {
+			break
+		}
+		if m.MaxSize > 0 && length > uint64(m.MaxSize) {
+			return nil, fmt.Errorf("chunk of %d bytes larger than the max size: %w", length, ErrInvalidManifest)
+		}
+		ref := store.ChunkRef{Offset: offset, Length: int(length)}
+		if _, err := io.ReadFull(cr, ref.Digest[:]); err != nil {
+			return nil, cr.Unexpected(err)
+		}
+		m.Chunks = append(m.Chunks, ref)
+		offset += int64(length)
+	}
+
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return nil, cr.Unexpected(err)
+	}
+	if count != uint64(len(m.Chunks)) {
+		return nil, fmt.Errorf("chunk count %d, want %d: %w", len(m.Chunks), count, ErrInvalidManifest)
+	}
+
+	sum := cr.Sum32()
+	var b [4]byte
+	if _, err := io.ReadFull(cr, b[:]); err != nil {
+		return nil, cr.Unexpected(err)
+	}
+	if binary.BigEndian.Uint32(b[:]) != sum {
+		return nil, fmt.Errorf("checksum mismatch: %w", ErrInvalidManifest)
+	}
+	return m, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
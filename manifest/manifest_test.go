@@ -0,0 +1,319 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/tigerwill90/fastcdc/v2"
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+func sekienData(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../fixtures/SekienAkashita.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func randomData(seed uint64, size int) []byte {
+	rng := rand.New(rand.NewPCG(seed, 0))
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+	return data
+}
+
+func TestEncodeDecode(t *testing.T) {
+	data := sekienData(t)
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, chunker)
+	for chunk, err := range chunker.Chunks(bytes.NewReader(data)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Add(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Decode(iotest.OneByteReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Header != HeaderOf(chunker) {
+		t.Errorf("header: want = %v, got = %v", HeaderOf(chunker), m.Header)
+	}
+	if m.Size() != int64(len(data)) {
+		t.Errorf("size: want = %d, got = %d", len(data), m.Size())
+	}
+
+	refs, err := store.StoreStream(chunker, bytes.NewReader(data), store.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m.Chunks, refs) {
+		t.Errorf("chunks: want = %v, got = %v", refs, m.Chunks)
+	}
+
+	// WriteTo produces the same encoding.
+	var other bytes.Buffer
+	n, err := New(chunker, refs).WriteTo(&other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(other.Len()) || !bytes.Equal(other.Bytes(), buf.Bytes()) {
+		t.Errorf("WriteTo: want = %x, got = %x", buf.Bytes(), other.Bytes())
+	}
+}
+
+func TestEncodeEmpty(t *testing.T) {
+	chunker, err := fastcdc.NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := New(chunker, nil).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Chunks) != 0 || m.Size() != 0 {
+		t.Errorf("want an empty manifest, got = %v", m)
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	chunker, err := fastcdc.NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := NewEncoder(io.Discard, chunker)
+	if err := enc.AddRef(store.ChunkRef{Offset: 10, Length: 10}); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("gap: want = %s, got = %s", ErrInvalidManifest, err)
+	}
+	if err := enc.AddRef(store.ChunkRef{Offset: 0, Length: 0}); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("empty chunk: want = %s, got = %s", ErrInvalidManifest, err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.AddRef(store.ChunkRef{Offset: 0, Length: 10}); !errors.Is(err, ErrEncoderClosed) {
+		t.Errorf("closed: want = %s, got = %s", ErrEncoderClosed, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := randomData(7, 1<<20)
+	refs, err := store.StoreStream(chunker, bytes.NewReader(data), store.NewMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := New(chunker, refs).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	badMagic := slices.Clone(valid)
+	badMagic[0] = 'X'
+	version := slices.Clone(valid)
+	version[len(magic)] = 2
+	flipped := slices.Clone(valid)
+	flipped[len(valid)/2] ^= 1
+
+	tests := map[string][]byte{
+		"empty":           nil,
+		"bad magic":       badMagic,
+		"unknown version": version,
+		"truncated":       valid[:len(valid)-5],
+		"corrupted":       flipped,
+	}
+
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(b)); !errors.Is(err, ErrInvalidManifest) {
+				t.Errorf("want = %s, got = %s", ErrInvalidManifest, err)
+			}
+		})
+	}
+
+	t.Run("read error", func(t *testing.T) {
+		readErr := errors.New("read error")
+		r := io.MultiReader(bytes.NewReader(valid[:100]), iotest.ErrReader(readErr))
+		if _, err := Decode(r); !errors.Is(err, readErr) {
+			t.Errorf("want = %s, got = %s", readErr, err)
+		}
+	})
+}
+
+func newReassembler(t *testing.T, data []byte) (*Reassembler, store.ChunkStore) {
+	t.Helper()
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemory()
+	refs, err := store.StoreStream(chunker, bytes.NewReader(data), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewReassembler(New(chunker, refs), s), s
+}
+
+func TestReassembler(t *testing.T) {
+	data := randomData(11, 1<<20)
+	r, _ := newReassembler(t, data)
+
+	if r.Size() != int64(len(data)) {
+		t.Errorf("size: want = %d, got = %d", len(data), r.Size())
+	}
+	if err := iotest.TestReader(r, data); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(iotest.HalfReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("the reassembled stream does not match the data")
+	}
+}
+
+func TestReassemblerReadAt(t *testing.T) {
+	data := randomData(13, 1<<20)
+	r, _ := newReassembler(t, data)
+	rng := rand.New(rand.NewPCG(13, 0))
+
+	for range 200 {
+		off := rng.IntN(len(data) + 100)
+		p := make([]byte, rng.IntN(100_000))
+		n, err := r.ReadAt(p, int64(off))
+
+		want := data[min(off, len(data)):min(off+len(p), len(data))]
+		if !bytes.Equal(p[:n], want) {
+			t.Fatalf("ReadAt(%d, %d): content mismatch", off, len(p))
+		}
+		if n < len(p) && err != io.EOF {
+			t.Fatalf("ReadAt(%d, %d): want = %s, got = %v", off, len(p), io.EOF, err)
+		}
+	}
+
+	if _, err := r.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Error("negative offset: want an error")
+	}
+}
+
+// barrierStore blocks every Get until n Gets are in progress.
+type barrierStore struct {
+	store.ChunkStore
+	wg *sync.WaitGroup
+}
+
+func (s barrierStore) Get(d store.Digest) ([]byte, error) {
+	s.wg.Done()
+	s.wg.Wait()
+	return s.ChunkStore.Get(d)
+}
+
+// TestReassemblerConcurrentReadAt checks that concurrent ReadAt calls
+// fetch their chunks from the store in parallel.
+func TestReassemblerConcurrentReadAt(t *testing.T) {
+	data := randomData(19, 1<<20)
+	r, s := newReassembler(t, data)
+	if len(r.m.Chunks) < 4 {
+		t.Fatalf("chunks: want >= 4, got = %d", len(r.m.Chunks))
+	}
+
+	const readers = 4
+	var wg sync.WaitGroup
+	wg.Add(readers)
+	concurrent := NewReassembler(r.m, barrierStore{s, &wg})
+
+	errs := make(chan error, readers)
+	for i := range readers {
+		go func() {
+			ref := r.m.Chunks[i]
+			p := make([]byte, ref.Length)
+			if _, err := concurrent.ReadAt(p, ref.Offset); err != nil {
+				errs <- err
+				return
+			}
+			if !bytes.Equal(p, data[ref.Offset:ref.Offset+int64(ref.Length)]) {
+				errs <- fmt.Errorf("chunk %d: content mismatch", i)
+				return
+			}
+			errs <- nil
+		}()
+	}
+	for range readers {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("the fetches are serialized")
+		}
+	}
+}
+
+// corruptStore returns another content for every chunk.
+type corruptStore struct {
+	store.ChunkStore
+}
+
+func (s corruptStore) Get(d store.Digest) ([]byte, error) {
+	data, err := s.ChunkStore.Get(d)
+	if err != nil {
+		return nil, err
+	}
+	data = slices.Clone(data)
+	data[0] ^= 1
+	return data, nil
+}
+
+func TestReassemblerErrors(t *testing.T) {
+	data := randomData(17, 200_000)
+	r, s := newReassembler(t, data)
+
+	corrupted := NewReassembler(r.m, corruptStore{s})
+	if _, err := io.ReadAll(corrupted); !errors.Is(err, ErrCorruptChunk) {
+		t.Errorf("want = %s, got = %s", ErrCorruptChunk, err)
+	}
+
+	missing := NewReassembler(r.m, store.NewMemory())
+	if _, err := io.ReadAll(missing); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("want = %s, got = %s", store.ErrNotFound, err)
+	}
+}
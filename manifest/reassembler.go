@@ -0,0 +1,122 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/tigerwill90/fastcdc/v2/store"
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// Reassembler reads the stream described by a manifest from a chunk
+// store. The chunks are fetched lazily, when a read reaches them, and
+// verified against their digest. It implements io.ReadSeeker and
+// io.ReaderAt. ReadAt is safe for concurrent use, Read and Seek are not.
+type Reassembler struct {
+	m      *Manifest
+	s      store.ChunkStore
+	size   int64
+	offset int64
+
+	mu    sync.Mutex // guards the cache
+	index int        // index of the cached chunk, or -1
+	data  []byte
+}
+
+// NewReassembler returns a reader of the stream described by m, reading
+// the chunks from s. It reads the stream from the start.
+func NewReassembler(m *Manifest, s store.ChunkStore) *Reassembler {
+	return &Reassembler{m: m, s: s, size: m.Size(), index: -1}
+}
+
+// Size returns the size of the stream.
+func (r *Reassembler) Size() int64 {
+	return r.size
+}
+
+// Read implements io.Reader.
+func (r *Reassembler) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *Reassembler) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// ReadAt implements io.ReaderAt. It returns ErrCorruptChunk if a chunk
+// fetched from the store does not match the manifest.
+func (r *Reassembler) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+
+	chunks := r.m.Chunks
+	// The chunk containing off.
+	i := sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].Offset+int64(chunks[i].Length) > off
+	})
+
+	var n int
+	for n < len(p) {
+		if i >= len(chunks) {
+			return n, io.EOF
+		}
+		data, err := r.chunk(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[off+int64(n)-chunks[i].Offset:])
+		i++
+	}
+	return n, nil
+}
+
+// chunk returns the content of the chunk i, from the cache or from the
+// store. The chunk is fetched and verified without holding r.mu, so that
+// concurrent ReadAt calls do not wait for each other's fetches. The cached
+// content is never modified, only replaced.
+func (r *Reassembler) chunk(i int) ([]byte, error) {
+	r.mu.Lock()
+	index, data := r.index, r.data
+	r.mu.Unlock()
+	if index == i {
+		return data, nil
+	}
+
+	ref := r.m.Chunks[i]
+	data, err := r.s.Get(ref.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s at offset %d: %w", ref.Digest, ref.Offset, err)
+	}
+	if len(data) != ref.Length || store.Sum(data) != ref.Digest {
+		return nil, fmt.Errorf("chunk %s at offset %d: %w", ref.Digest, ref.Offset, ErrCorruptChunk)
+	}
+
+	r.mu.Lock()
+	r.index, r.data = i, data
+	r.mu.Unlock()
+	return data, nil
+}
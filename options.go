@@ -1,12 +1,28 @@
 package fastcdc
 
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"hash/crc64"
+)
+
 type Option func(*config)
 
 type config struct {
-	bufferSize uint
-	minSize    uint
-	avgSize    uint
-	maxSize    uint
+	bufferSize    uint
+	minSize       uint
+	avgSize       uint
+	maxSize       uint
+	newHash       func() hash.Hash
+	algorithm     Algorithm
+	key           []byte
+	normalization uint
+	table         *[256]uint64
+	ronomon       bool
+	polynomial    uint64
+	window        uint
+	seed          *uint32
 }
 
 func defaultConfig() *config {
@@ -14,6 +30,8 @@ func defaultConfig() *config {
 		minSize: 16_384,
 		avgSize: 65_536,
 		maxSize: 524_288,
+		// 1 bit normalization, like ronomon/deduplication.
+		normalization: 1,
 	}
 }
 
@@ -66,3 +84,136 @@ func With64kChunks() Option {
 		c.maxSize = 524_288
 	}
 }
+
+// WithAlgorithm set the cut point algorithm. The algorithms produce
+// different chunks for the same input and configuration, so the same
+// algorithm must be used to deduplicate against existing chunks.
+// Default is FastCDC2016.
+func WithAlgorithm(a Algorithm) Option {
+	return func(c *config) {
+		c.algorithm = a
+	}
+}
+
+// WithNormalization set the normalization level of the chunking, i.e.
+// the number of bits added to the mask used below the normal size, and
+// removed from the mask used above. Level 0 disables the normalization.
+// The higher the level, the closer the chunk sizes are to the average,
+// at the cost of a lower deduplication ratio. The 2016 FastCDC paper
+// evaluates the levels 0 to 3. The level must be lower than the base 2
+// logarithm of the average size, and their sum must not exceed 31.
+// Default is 1.
+func WithNormalization(level uint) Option {
+	return func(c *config) {
+		c.normalization = level
+	}
+}
+
+// WithKey set a secret key from which the gear table is derived. The
+// default gear table is public, so the chunk lengths of a known file can
+// be predicted and used to confirm that it is stored, even if the chunks
+// are encrypted. A keyed chunker produces chunks which are deterministic
+// for a given key but cannot be predicted without it. The key must not
+// be empty.
+func WithKey(key []byte) Option {
+	return func(c *config) {
+		c.key = key
+		if c.key == nil {
+			// Keep WithKey(nil) apart from no key, so it is rejected.
+			c.key = []byte{}
+		}
+	}
+}
+
+// WithGearTable set a custom gear table, for example to produce the same
+// chunks as another implementation. The table is copied. It must map
+// every byte to a distinct value, and each of the low 31 bits, used by
+// the masks, must vary across the table. A custom gear table and WithKey
+// are mutually exclusive. Default is the gear table of
+// ronomon/deduplication.
+func WithGearTable(t *[256]uint64) Option {
+	return func(c *config) {
+		if t == nil {
+			// Keep WithGearTable(nil) apart from no table, so it is
+			// rejected.
+			c.table = &[256]uint64{}
+			return
+		}
+		copied := *t
+		c.table = &copied
+	}
+}
+
+// WithRonomonCompat guarantees the cut points of ronomon/deduplication,
+// and of the implementations which follow it like the ronomon variant of
+// the fastcdc Rust crate, for the same min, avg and max sizes. It
+// requires the FastCDC2016 algorithm, the normalization level 1 and the
+// built-in gear table, which are the defaults, and makes NewChunker
+// reject any option which would change them.
+func WithRonomonCompat() Option {
+	return func(c *config) {
+		c.ronomon = true
+	}
+}
+
+// WithPolynomial set the irreducible polynomial of the Rabin fingerprint
+// of a RabinChunker. Bit i of p is the coefficient of x^i, and the degree
+// must be within [32, 56]. Chunkers with different polynomials produce
+// different chunks. Default is 0x3DA3358B4DC173, of degree 53.
+func WithPolynomial(p uint64) Option {
+	return func(c *config) {
+		c.polynomial = p
+	}
+}
+
+// WithWindowSize set the size of the rolling window of a RabinChunker or
+// a BuzhashChunker, in bytes. A cut point only depends on the window size
+// bytes before it. The window size must be within [1, min size]. Default
+// is 64 for RabinChunker, and 4095 or the min size if smaller for
+// BuzhashChunker.
+func WithWindowSize(n uint) Option {
+	return func(c *config) {
+		c.window = n
+	}
+}
+
+// WithSeed set the seed from which the buzhash table of a BuzhashChunker
+// is generated. Chunkers with different seeds produce different chunks.
+// Default is 0.
+func WithSeed(seed uint32) Option {
+	return func(c *config) {
+		c.seed = &seed
+	}
+}
+
+// WithDigest set the hash function used to compute the digest of every
+// chunk. The digest is computed while the chunk is hot in cache, right
+// after its cut point is found, and is reported in Chunk.Sum.
+// Default is no digest.
+func WithDigest(newHash func() hash.Hash) Option {
+	return func(c *config) {
+		c.newHash = newHash
+	}
+}
+
+// WithSHA256Digest set the SHA-256 chunk digest preset.
+func WithSHA256Digest() Option {
+	return WithDigest(sha256.New)
+}
+
+// WithSHA512_256Digest set the SHA-512/256 chunk digest preset. It is
+// usually faster than SHA-256 on 64-bit platforms without SHA-256
+// hardware acceleration.
+func WithSHA512_256Digest() Option {
+	return WithDigest(sha512.New512_256)
+}
+
+// WithCRC64Digest set the non-cryptographic CRC-64 (ECMA) chunk digest
+// preset. It is fast but must not be used to address chunks.
+func WithCRC64Digest() Option {
+	return WithDigest(func() hash.Hash {
+		return crc64.New(crcTable)
+	})
+}
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
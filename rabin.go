@@ -0,0 +1,225 @@
+package fastcdc
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Bounds within which the polynomial degree of a RabinChunker must fit.
+// The fingerprint, shifted one byte left, must fit in 64 bits, and the
+// polynomial must have more bits than the mask.
+const (
+	polynomialDegreeMin = 32
+	polynomialDegreeMax = 56
+)
+
+const (
+	defaultPolynomial      uint64 = 0x3DA3358B4DC173
+	defaultRabinWindowSize uint   = 64
+)
+
+// RabinChunker splits a stream into content-defined chunks with a Rabin
+// fingerprint over a rolling window, like LBFS and restic. A cut point is
+// declared where the low bits of the fingerprint are all zero.
+//
+// It shares the chunk size validation, the buffer management and the
+// invariants of Chunker, which it embeds: the chunks are within [min,
+// max], except the last one, and identical input with an identical
+// configuration always produces identical chunks, whatever the buffer size
+// or the read pattern of the reader. The expected chunk size is min plus
+// the nearest power of two of avg - min.
+type RabinChunker struct {
+	*Chunker
+}
+
+// NewRabinChunker returns a Rabin fingerprint chunker. It accepts the
+// chunk size, buffer size and digest options of NewChunker, along with
+// WithPolynomial and WithWindowSize. The options specific to the FastCDC
+// algorithms, like WithAlgorithm, WithNormalization, WithKey or
+// WithGearTable, are rejected.
+func NewRabinChunker(opts ...Option) (*RabinChunker, error) {
+	c, err := newRollingChunker(rabin, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &RabinChunker{c}, nil
+}
+
+// newRollingChunker returns a chunker with the rolling hash algorithm of
+// RabinChunker or BuzhashChunker, which do not take an algorithm option.
+func newRollingChunker(algo Algorithm, opts []Option) (*Chunker, error) {
+	config := defaultConfig()
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.algorithm != FastCDC2016 {
+		return nil, fmt.Errorf("the %s chunker does not take an algorithm: %w", algo, ErrInvalidAlgorithm)
+	}
+	config.algorithm = algo
+
+	return newChunker(config)
+}
+
+// initRollingWindow sets up the window and the mask of a rolling hash,
+// shared by RabinChunker and BuzhashChunker.
+func (c *Chunker) initRollingWindow(config *config, defaultWindow uint) error {
+	if config.normalization != 1 {
+		return fmt.Errorf("the %s chunker has a single mask: %w", config.algorithm, ErrInvalidNormalization)
+	}
+
+	window := config.window
+	if window == 0 {
+		window = defaultWindow
+	}
+	if window > config.minSize {
+		return fmt.Errorf("the window size must be within [1, %d]: %w", config.minSize, ErrInvalidWindowSize)
+	}
+
+	c.window = window
+	c.skip = config.minSize - window
+	// A single mask, such that the expected chunk size is close to the
+	// average.
+	c.maskS = mask(max(logarithm2(config.avgSize-config.minSize), 1))
+	c.maskL = c.maskS
+	return nil
+}
+
+// rabinTables are the precomputed tables of a Rabin fingerprint, as in
+// restic/chunker.
+type rabinTables struct {
+	pol   uint64
+	shift uint // degree of pol - 8
+	// out[b] is the fingerprint of b followed by window size - 1 zero
+	// bytes, to remove b from the window.
+	out [256]uint64
+	// mod[b] reduces a fingerprint whose top byte above the degree of pol
+	// is b.
+	mod [256]uint64
+}
+
+// initRabin sets up the tables and the mask of the Rabin fingerprint.
+func (c *Chunker) initRabin(config *config) error {
+	if config.key != nil || config.table != nil {
+		return fmt.Errorf("the Rabin chunker does not use a gear table: %w", ErrInvalidGearTable)
+	}
+	if config.seed != nil {
+		return fmt.Errorf("the seed only applies to the buzhash chunker: %w", ErrInvalidAlgorithm)
+	}
+
+	pol := config.polynomial
+	if pol == 0 {
+		pol = defaultPolynomial
+	}
+	if deg := polDeg(pol); deg < polynomialDegreeMin || deg > polynomialDegreeMax {
+		return fmt.Errorf("the polynomial degree must be within [%d, %d]: %w", polynomialDegreeMin, polynomialDegreeMax, ErrInvalidPolynomial)
+	}
+	if !polIrreducible(pol) {
+		return fmt.Errorf("the polynomial %#x is reducible: %w", pol, ErrInvalidPolynomial)
+	}
+
+	if err := c.initRollingWindow(config, defaultRabinWindowSize); err != nil {
+		return err
+	}
+	c.rabin = newRabinTables(pol, c.window)
+	return nil
+}
+
+func newRabinTables(pol uint64, window uint) *rabinTables {
+	deg := polDeg(pol)
+	t := &rabinTables{pol: pol, shift: uint(deg - 8)}
+	for b := range 256 {
+		h := polMod(uint64(b), pol)
+		for range window - 1 {
+			h = polMod(h<<8, pol)
+		}
+		t.out[b] = h
+		// The top byte is cleared by the XOR with b<<deg, and replaced by
+		// its remainder.
+		t.mod[b] = polMod(uint64(b)<<deg, pol) | uint64(b)<<deg
+	}
+	return t
+}
+
+// searchRabin is the cut point search of RabinChunker. The fingerprint
+// only depends on the window size bytes before a cut point, so the search
+// starts window size bytes before the min size.
+func (c *Chunker) searchRabin(window []byte) (uint, CutReason) {
+	t := c.rabin
+	shift, mask := t.shift, c.maskS
+
+	// Fill the rolling window. The bytes removed from an empty window are
+	// zeros, whose out entry is zero.
+	var digest uint64
+	for _, b := range window[c.skip:c.minSize] {
+		digest = (digest<<8 | uint64(b)) ^ t.mod[digest>>shift]
+	}
+
+	for cut := c.minSize; cut < uint(len(window)); cut++ {
+		digest ^= t.out[window[cut-c.window]]
+		digest = (digest<<8 | uint64(window[cut])) ^ t.mod[digest>>shift]
+		if digest&mask == 0 {
+			return cut + 1, CutContent
+		}
+	}
+	return 0, CutNone
+}
+
+// polDeg returns the degree of the polynomial p over GF(2), or -1 for 0.
+func polDeg(p uint64) int {
+	return bits.Len64(p) - 1
+}
+
+// polMod returns x modulo p.
+func polMod(x, p uint64) uint64 {
+	d := polDeg(p)
+	for dx := polDeg(x); dx >= d; dx = polDeg(x) {
+		x ^= p << (dx - d)
+	}
+	return x
+}
+
+// polMulMod returns a * b modulo p, where a and b are already reduced
+// modulo p and the degree of p is lower than 64.
+func polMulMod(a, b, p uint64) uint64 {
+	d := polDeg(p)
+	var r uint64
+	for ; b != 0; b >>= 1 {
+		if b&1 != 0 {
+			r ^= a
+		}
+		a <<= 1
+		if a>>d&1 != 0 {
+			a ^= p
+		}
+	}
+	return r
+}
+
+// polGCD returns the greatest common divisor of a and b.
+func polGCD(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, polMod(a, b)
+	}
+	return a
+}
+
+// polIrreducible reports whether p is irreducible over GF(2), with the
+// Ben-Or test: p of degree d is irreducible if and only if x^(2^i) - x is
+// coprime with p for every i in [1, d/2].
+func polIrreducible(p uint64) bool {
+	d := polDeg(p)
+	if d < 2 {
+		return d == 1
+	}
+	const x = 2 // the polynomial x
+	h := uint64(x)
+	for range d / 2 {
+		h = polMulMod(h, h, p)
+		if polGCD(p, h^x) != 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var sekienRabinGoldens = map[string]sekienGolden{
+	"16kChunks": {
+		Preset:  With16kChunks(),
+		MaxSize: 131_072,
+		Want:    []chunkInfo{{0, 21168}, {21168, 41245}, {62413, 20639}, {83052, 26414}},
+	},
+	"8k16k32kChunks": {
+		Preset:  WithChunksSize(8192, 16_384, 32_768),
+		MaxSize: 32_768,
+		Want: []chunkInfo{
+			{0, 10979}, {10979, 9792}, {20771, 14430}, {35201, 25100},
+			{60301, 16481}, {76782, 10939}, {87721, 21745},
+		},
+	},
+}
+
+// newRabinChunker returns the Chunker of a RabinChunker.
+func newRabinChunker(opts ...Option) (*Chunker, error) {
+	c, err := NewRabinChunker(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Chunker, nil
+}
+
+func TestPolIrreducible(t *testing.T) {
+	cases := map[string]struct {
+		Pol  uint64
+		Want bool
+	}{
+		"x+1":           {0x3, true},
+		"x^2+1":         {0x5, false},
+		"x^2+x+1":       {0x7, true},
+		"x^3+x+1":       {0xb, true},
+		"aes":           {0x11b, true},
+		"x^4+x^2+1":     {0x15, false},
+		"default":       {defaultPolynomial, true},
+		"default*(x+1)": {defaultPolynomial<<1 ^ defaultPolynomial, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := polIrreducible(tc.Pol); got != tc.Want {
+				t.Errorf("irreducible: want = %v, got = %v", tc.Want, got)
+			}
+		})
+	}
+}
+
+func TestRabinChunkerValidation(t *testing.T) {
+	cases := map[string]struct {
+		Opts []Option
+		Err  error
+	}{
+		"default":             {nil, nil},
+		"custom polynomial":   {[]Option{WithPolynomial(0x3DA3358B4DC173)}, nil},
+		"custom window":       {[]Option{WithWindowSize(48)}, nil},
+		"window of min size":  {[]Option{WithChunksSize(64, 256, 1024), WithWindowSize(64)}, nil},
+		"window over min":     {[]Option{WithChunksSize(64, 256, 1024), WithWindowSize(65)}, ErrInvalidWindowSize},
+		"reducible":           {[]Option{WithPolynomial(defaultPolynomial<<1 ^ defaultPolynomial)}, ErrInvalidPolynomial},
+		"degree too low":      {[]Option{WithPolynomial(0x11b)}, ErrInvalidPolynomial},
+		"degree too high":     {[]Option{WithPolynomial(1 << 60)}, ErrInvalidPolynomial},
+		"invalid chunk size":  {[]Option{WithChunksSize(32, 256, 1024)}, ErrInvalidChunkSize},
+		"invalid buffer size": {[]Option{WithBufferSize(1024)}, ErrInvalidBufferSize},
+		"algorithm":           {[]Option{WithAlgorithm(FastCDC2020)}, ErrInvalidAlgorithm},
+		"normalization":       {[]Option{WithNormalization(2)}, ErrInvalidNormalization},
+		"key":                 {[]Option{WithKey([]byte("secret"))}, ErrInvalidGearTable},
+		"gear table":          {[]Option{WithGearTable(&table)}, ErrInvalidGearTable},
+		"ronomon":             {[]Option{WithRonomonCompat()}, ErrInvalidAlgorithm},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRabinChunker(tc.Opts...)
+			if !errors.Is(err, tc.Err) {
+				t.Errorf("error: want = %v, got = %v", tc.Err, err)
+			}
+		})
+	}
+}
+
+func TestRabinOptionsRejectedByNewChunker(t *testing.T) {
+	for _, opt := range []Option{WithPolynomial(defaultPolynomial), WithWindowSize(64)} {
+		if _, err := NewChunker(opt); !errors.Is(err, ErrInvalidAlgorithm) {
+			t.Errorf("error: want = %v, got = %v", ErrInvalidAlgorithm, err)
+		}
+	}
+}
+
+func TestSekienRabinChunks(t *testing.T) {
+	data := sekienData(t)
+	for name, tc := range sekienRabinGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewRabinChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks := chunkAll(t, chunker.Chunker, bytes.NewReader(data), data)
+			if !slices.Equal(chunks, tc.Want) {
+				t.Errorf("chunks: want = %v, got = %v", tc.Want, chunks)
+			}
+		})
+	}
+}
+
+// TestRabinFingerprint checks every content cut point against the Rabin
+// fingerprint of the window before it, computed from scratch.
+func TestRabinFingerprint(t *testing.T) {
+	data := randomData(23, 4<<20)
+	chunker, err := NewRabinChunker(With16kChunks(), WithWindowSize(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, window, mask := chunker.rabin.pol, int(chunker.window), chunker.maskS
+
+	var content int
+	for chunk := range chunker.ChunkBytes(data) {
+		n := len(chunk.Data)
+		if chunk.Reason == CutEOF {
+			continue
+		}
+		if n < 4096 || n > 131_072 {
+			t.Fatalf("chunk size out of bounds: %d", n)
+		}
+		if chunk.Reason != CutContent {
+			continue
+		}
+		content++
+		var fp uint64
+		for _, b := range chunk.Data[n-window:] {
+			fp = polMod(fp<<8|uint64(b), pol)
+		}
+		if fp&mask != 0 {
+			t.Fatalf("fingerprint at offset %d: want = %#x masked to 0, got = %#x", chunk.Offset+int64(n), fp, fp&mask)
+		}
+	}
+	if content == 0 {
+		t.Error("no content cut point")
+	}
+}
+
+func TestRabinConfigFingerprint(t *testing.T) {
+	gear, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprints := map[uint64]string{gear.Fingerprint(): "gear"}
+	for name, opts := range map[string][]Option{
+		"default":    {With16kChunks()},
+		"window":     {With16kChunks(), WithWindowSize(32)},
+		"polynomial": {With16kChunks(), WithPolynomial(0x3e1c4c4c2a6ba7)},
+	} {
+		chunker, err := NewRabinChunker(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := fingerprints[chunker.Fingerprint()]; ok {
+			t.Errorf("fingerprint: %s and %s share %#x", name, other, chunker.Fingerprint())
+		}
+		fingerprints[chunker.Fingerprint()] = name
+	}
+}
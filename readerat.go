@@ -0,0 +1,156 @@
+package fastcdc
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// segment is a part of the input chunked by a worker.
+type segment struct {
+	start  int64   // stream position of the segment
+	end    int64   // end of the segment, exclusive
+	data   []byte  // input from start to end plus max size bytes of lookahead
+	bounds []int64 // chunk boundaries found by the worker from start
+	sums   []byte  // digests of the chunks found by the worker
+	// reasons are the cut reasons of the chunks found by the worker.
+	reasons []CutReason
+	err     error
+	done    chan struct{}
+}
+
+// ChunkReaderAt returns an iterator that chunks the first size bytes of
+// r in parallel and yields the chunks in order. It produces exactly the
+// same chunks as Chunks(io.NewSectionReader(r, 0, size)).
+//
+// The input is split into segments that are chunked concurrently by up
+// to workers goroutines, or runtime.GOMAXPROCS(0) if workers <= 0. A
+// worker starts at an arbitrary position of the input, so its first
+// boundaries may not match the sequential ones. Since a cut point only
+// depends on the bytes after the previous boundary, the chunking is
+// resynchronised at every segment seam: the chunks are cut sequentially
+// from the last chunk of the previous segment until they reach a
+// boundary found by the worker, from which the worker boundaries are the
+// sequential ones.
+//
+// The chunk digests, if any, are computed by the workers as well. The
+// yielded Chunk.Data and Chunk.Sum are only valid for the current
+// iteration and must be copied for later use. On a read error, the
+// iterator yields a zero Chunk with the error and stops. No goroutine
+// outlives the iteration. Since the internal buffer is not used,
+// ChunkReaderAt can run concurrently with any other iteration on the same
+// chunker.
+func (c *Chunker) ChunkReaderAt(r io.ReaderAt, size int64, workers int) iter.Seq2[Chunk, error] {
+	// Segments much larger than max size keep the sequential
+	// resynchronisation work negligible.
+	return c.chunkReaderAt(r, size, workers, max(16<<20, 4*int64(c.maxSize)))
+}
+
+func (c *Chunker) chunkReaderAt(r io.ReaderAt, size int64, workers int, segmentSize int64) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		if size < 0 {
+			yield(Chunk{}, errors.New("fastcdc: negative size"))
+			return
+		}
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		// The queue holds the segments in order. Together with the segment
+		// being consumed, at most workers segments are in flight.
+		queue := make(chan *segment, workers-1)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer close(done)
+
+		wg.Go(func() {
+			defer close(queue)
+			for start := int64(0); start < size; start += segmentSize {
+				s := &segment{start: start, end: min(start+segmentSize, size), done: make(chan struct{})}
+				select {
+				case queue <- s:
+				case <-done:
+					return
+				}
+				wg.Go(func() {
+					c.chunkSegment(r, size, s)
+				})
+			}
+		})
+
+		d := c.newDigester()
+		var pos int64 // stream position of the next chunk
+		for s := range queue {
+			<-s.done
+			if s.err != nil {
+				yield(Chunk{}, s.err)
+				return
+			}
+
+			var i int
+			for pos < s.end {
+				for i < len(s.bounds) && s.bounds[i] < pos {
+					i++
+				}
+
+				var next int64
+				var sum []byte
+				var reason CutReason
+				if i < len(s.bounds)-1 && s.bounds[i] == pos {
+					// In sync with the worker, its next boundary is the
+					// sequential one.
+					next = s.bounds[i+1]
+					reason = s.reasons[i]
+					if d.h != nil {
+						n := d.h.Size()
+						sum = s.sums[i*n : (i+1)*n]
+					}
+				} else {
+					// Not in sync yet, cut the next chunk sequentially. The
+					// segment data holds max size bytes of lookahead past the
+					// segment end, so the cut point is the sequential one.
+					var length uint
+					length, reason = c.nextCut(s.data[pos-s.start:])
+					next = pos + int64(length)
+					sum = d.digest(s.data[pos-s.start : next-s.start])
+				}
+
+				if !yield(Chunk{Offset: pos, Data: s.data[pos-s.start : next-s.start], Sum: sum, Reason: reason}, nil) {
+					return
+				}
+				pos = next
+			}
+		}
+	}
+}
+
+// chunkSegment reads the segment with its lookahead and finds the chunk
+// boundaries from the segment start, up to the first chunk which ends
+// past the segment end.
+func (c *Chunker) chunkSegment(r io.ReaderAt, size int64, s *segment) {
+	defer close(s.done)
+
+	s.data = make([]byte, min(s.end+int64(c.maxSize), size)-s.start)
+	n, err := r.ReadAt(s.data, s.start)
+	if n < len(s.data) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		s.err = err
+		return
+	}
+
+	d := c.newDigester()
+	pos := s.start
+	s.bounds = append(s.bounds, pos)
+	for pos < s.end {
+		length, reason := c.nextCut(s.data[pos-s.start:])
+		s.reasons = append(s.reasons, reason)
+		s.sums = append(s.sums, d.digest(s.data[pos-s.start:pos-s.start+int64(length)])...)
+		pos += int64(length)
+		s.bounds = append(s.bounds, pos)
+	}
+}
@@ -0,0 +1,191 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// chunkAllAt drains the parallel chunking of input and verifies that the
+// chunks are contiguous and match the input content.
+func chunkAllAt(t *testing.T, chunker *Chunker, input []byte, workers int, segmentSize int64) []chunkInfo {
+	t.Helper()
+	var chunks []chunkInfo
+	var pos int64
+	for chunk, err := range chunker.chunkReaderAt(bytes.NewReader(input), int64(len(input)), workers, segmentSize) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Offset != pos {
+			t.Fatalf("offset: want = %d, got = %d", pos, chunk.Offset)
+		}
+		if !bytes.Equal(chunk.Data, input[chunk.Offset:chunk.Offset+int64(len(chunk.Data))]) {
+			t.Fatalf("chunk content mismatch at offset %d", chunk.Offset)
+		}
+		chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+		pos += int64(len(chunk.Data))
+	}
+	if pos != int64(len(input)) {
+		t.Fatalf("stream coverage: want = %d bytes, got = %d", len(input), pos)
+	}
+	return chunks
+}
+
+func TestSekienChunkReaderAt(t *testing.T) {
+	data := sekienData(t)
+
+	for name, tc := range sekienGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, workers := range []int{1, 2, 4, 8} {
+				for _, segmentSize := range []int64{100, 1000, 7777, 40_000, int64(len(data))} {
+					chunks := chunkAllAt(t, chunker, data, workers, segmentSize)
+					if !slices.Equal(chunks, tc.Want) {
+						t.Errorf("%d workers, %d bytes segments: chunks: want = %v, got = %v", workers, segmentSize, tc.Want, chunks)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestChunkReaderAtRandomInput checks on random input that the parallel
+// chunking produces the same chunks as Chunks, whatever the number of
+// workers and the segment size.
+func TestChunkReaderAtRandomInput(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 50 {
+		data := randomData(rng.Uint64(), rng.IntN(4<<20))
+		want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+		workers := 1 + rng.IntN(8)
+		segmentSize := 4096 + rng.Int64N(1<<20)
+		got := chunkAllAt(t, chunker, data, workers, segmentSize)
+		if !slices.Equal(got, want) {
+			t.Fatalf("chunks: want = %v, got = %v, input size = %d, workers = %d, segment size = %d", want, got, len(data), workers, segmentSize)
+		}
+	}
+}
+
+func TestChunkReaderAt(t *testing.T) {
+	data := randomData(11, 40<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chunkAll(t, chunker, bytes.NewReader(data), data)
+
+	var got []chunkInfo
+	for chunk, err := range chunker.ChunkReaderAt(bytes.NewReader(data), int64(len(data)), 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, chunkInfo{chunk.Offset, len(chunk.Data)})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("chunks: want = %v, got = %v", want, got)
+	}
+}
+
+// failingReaderAt fails with err for any read past limit.
+type failingReaderAt struct {
+	r     io.ReaderAt
+	limit int64
+	err   error
+}
+
+func (f *failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.limit {
+		return 0, f.err
+	}
+	return f.r.ReadAt(p, off)
+}
+
+func TestChunkReaderAtReadError(t *testing.T) {
+	sentinel := errors.New("read failure")
+	data := randomData(42, 4<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		R    io.ReaderAt
+		Size int64
+		Want error
+	}{
+		"read failure": {
+			R:    &failingReaderAt{r: bytes.NewReader(data), limit: 2 << 20, err: sentinel},
+			Size: int64(len(data)),
+			Want: sentinel,
+		},
+		"short input": {
+			R:    bytes.NewReader(data),
+			Size: int64(len(data)) + 1,
+			Want: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var chunks int
+			var gotErr error
+			for chunk, err := range chunker.chunkReaderAt(tc.R, tc.Size, 4, 1<<20) {
+				if err != nil {
+					gotErr = err
+					if chunk.Offset != 0 || len(chunk.Data) != 0 {
+						t.Error("chunk must be zero when an error is yielded")
+					}
+					continue
+				}
+				chunks++
+			}
+			if !errors.Is(gotErr, tc.Want) {
+				t.Errorf("want = %s, got = %s", tc.Want, gotErr)
+			}
+			if chunks == 0 {
+				t.Error("chunks found before the read failure must be yielded")
+			}
+		})
+	}
+}
+
+func TestChunkReaderAtEarlyBreak(t *testing.T) {
+	data := randomData(5, 8<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks int
+	for _, err := range chunker.chunkReaderAt(bytes.NewReader(data), int64(len(data)), 4, 1<<18) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks++
+		if chunks == 20 {
+			break
+		}
+	}
+	if chunks != 20 {
+		t.Errorf("chunks: want = 20, got = %d", chunks)
+	}
+}
@@ -0,0 +1,111 @@
+package fastcdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"testing/iotest"
+)
+
+type ronomonVector struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Input   string `json:"input"`
+	Min     uint   `json:"min"`
+	Avg     uint   `json:"avg"`
+	Max     uint   `json:"max"`
+	Lengths []int  `json:"lengths"`
+}
+
+// TestRonomonVectors checks the cut points of the ronomon compatibility
+// mode against the vectors of testdata/ronomon, whatever the buffer size
+// and the read pattern of the reader.
+func TestRonomonVectors(t *testing.T) {
+	b, err := os.ReadFile("testdata/ronomon/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []ronomonVector
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"full":     func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"7 bytes":  func(r io.Reader) io.Reader { return &chunkyReader{r, 7} },
+	}
+
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			data, err := os.ReadFile(v.Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []chunkInfo
+			var offset int64
+			for _, n := range v.Lengths {
+				want = append(want, chunkInfo{offset, n})
+				offset += int64(n)
+			}
+			if offset != int64(len(data)) {
+				t.Fatalf("the vector covers %d bytes of %d", offset, len(data))
+			}
+
+			for _, bufSize := range []uint{v.Max, 3*v.Max + 1} {
+				chunker, err := NewChunker(WithChunksSize(v.Min, v.Avg, v.Max), WithBufferSize(bufSize), WithRonomonCompat())
+				if err != nil {
+					t.Fatal(err)
+				}
+				for name, wrap := range readers {
+					if got := chunkAll(t, chunker, wrap(bytes.NewReader(data)), data); !slices.Equal(got, want) {
+						t.Errorf("%s reader, buffer size %d: chunks: want = %v, got = %v", name, bufSize, want, got)
+					}
+				}
+
+				var got []chunkInfo
+				for chunk := range chunker.ChunkBytes(data) {
+					got = append(got, chunkInfo{chunk.Offset, len(chunk.Data)})
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("ChunkBytes: chunks: want = %v, got = %v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRonomonCompatValidation(t *testing.T) {
+	tests := map[string][]Option{
+		"algorithm":     {WithAlgorithm(FastCDC2020)},
+		"normalization": {WithNormalization(2)},
+		"key":           {WithKey([]byte("foo"))},
+		"gear table":    {WithGearTable(&table)},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewChunker(append(opts, WithRonomonCompat())...)
+			if !errors.Is(err, ErrInvalidAlgorithm) {
+				t.Errorf("want = %s, got = %s", ErrInvalidAlgorithm, err)
+			}
+		})
+	}
+
+	// The compatibility mode does not change the chunks.
+	a, err := NewChunker(With16kChunks(), WithRonomonCompat(), WithAlgorithm(FastCDC2016), WithNormalization(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("the compatibility mode must not change the fingerprint")
+	}
+}
@@ -0,0 +1,25 @@
+package fastcdc
+
+import (
+	"io"
+	"iter"
+)
+
+// Splitter splits a stream into content-defined chunks. Chunker,
+// RabinChunker and BuzhashChunker implement it, so a pipeline can switch
+// from one algorithm to the other through its configuration.
+//
+// Chunks yields the chunks of the stream in order. Every chunk size is
+// within [min, max], except the last chunk of the stream which can be
+// smaller than min. On a read error, the iterator yields a zero Chunk
+// with the error and stops. The yielded Chunk.Data and Chunk.Sum are only
+// valid for the current iteration.
+type Splitter interface {
+	Chunks(r io.Reader) iter.Seq2[Chunk, error]
+}
+
+var (
+	_ Splitter = (*Chunker)(nil)
+	_ Splitter = (*RabinChunker)(nil)
+	_ Splitter = (*BuzhashChunker)(nil)
+)
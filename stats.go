@@ -0,0 +1,162 @@
+package fastcdc
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Stats records the chunk size distribution of the chunks yielded by a
+// chunker, to tune its chunk sizes. The zero value is ready to use. A
+// Stats is not safe for concurrent use.
+type Stats struct {
+	// Count is the number of chunks.
+	Count int64
+	// Bytes is the total size of the chunks.
+	Bytes int64
+	// Min and Max are the smallest and the largest chunk sizes.
+	Min int
+	Max int
+	// StrictCuts and LooseCuts are the number of chunks cut by their
+	// content, with the strict and the loose mask.
+	StrictCuts int64
+	LooseCuts  int64
+	// ContentCuts is the number of chunks cut by their content, by an
+	// algorithm without normalized chunking.
+	ContentCuts int64
+	// MaxSizeCuts is the number of chunks forced to the max size.
+	MaxSizeCuts int64
+	// EOFCuts is the number of chunks cut by the end of the stream.
+	EOFCuts int64
+	// Buckets is the log-scale histogram of the chunk sizes: Buckets[i]
+	// counts the chunks of [2^i, 2^(i+1)) bytes.
+	Buckets [64]int64
+
+	// mean and m2 are the running mean and sum of squared deviations of
+	// the chunk sizes (Welford's algorithm).
+	mean float64
+	m2   float64
+}
+
+// Collect returns an iterator that yields the chunks and the errors of
+// seq, recording the chunks in the statistics.
+//
+//	var stats fastcdc.Stats
+//	for chunk, err := range stats.Collect(chunker.Chunks(r)) {
+//		...
+//	}
+func (s *Stats) Collect(seq iter.Seq2[Chunk, error]) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		for chunk, err := range seq {
+			if err == nil {
+				s.Add(chunk)
+			}
+			if !yield(chunk, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Add records the chunk in the statistics.
+func (s *Stats) Add(chunk Chunk) {
+	n := len(chunk.Data)
+	if s.Count == 0 || n < s.Min {
+		s.Min = n
+	}
+	s.Max = max(s.Max, n)
+	s.Count++
+	s.Bytes += int64(n)
+	if n > 0 {
+		s.Buckets[bits.Len(uint(n))-1]++
+	}
+
+	delta := float64(n) - s.mean
+	s.mean += delta / float64(s.Count)
+	s.m2 += delta * (float64(n) - s.mean)
+
+	switch chunk.Reason {
+	case CutStrict:
+		s.StrictCuts++
+	case CutLoose:
+		s.LooseCuts++
+	case CutMaxSize:
+		s.MaxSizeCuts++
+	case CutEOF:
+		s.EOFCuts++
+	case CutContent:
+		s.ContentCuts++
+	}
+}
+
+// Mean returns the mean chunk size.
+func (s Stats) Mean() float64 {
+	return s.mean
+}
+
+// StdDev returns the standard deviation of the chunk sizes.
+func (s Stats) StdDev() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.Count))
+}
+
+// StatsBucket is a bucket of the chunk size histogram.
+type StatsBucket struct {
+	// Low and High are the bounds of the bucket, [Low, High).
+	Low   int64 `json:"low"`
+	High  int64 `json:"high"`
+	Count int64 `json:"count"`
+}
+
+// Histogram returns the non-empty buckets of the chunk size histogram,
+// by increasing size.
+func (s Stats) Histogram() []StatsBucket {
+	buckets := []StatsBucket{}
+	for i, n := range s.Buckets {
+		if n > 0 {
+			buckets = append(buckets, StatsBucket{Low: 1 << i, High: 1 << (i + 1), Count: n})
+		}
+	}
+	return buckets
+}
+
+// String returns a text report of the statistics, with a bar chart of
+// the histogram.
+func (s Stats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "chunks:  %d\n", s.Count)
+	fmt.Fprintf(&b, "bytes:   %d\n", s.Bytes)
+	fmt.Fprintf(&b, "min:     %d\n", s.Min)
+	fmt.Fprintf(&b, "max:     %d\n", s.Max)
+	fmt.Fprintf(&b, "mean:    %.0f\n", s.Mean())
+	fmt.Fprintf(&b, "stddev:  %.0f\n", s.StdDev())
+	fmt.Fprintf(&b, "cuts:    %d content, %d strict, %d loose, %d max size, %d end of stream\n", s.ContentCuts, s.StrictCuts, s.LooseCuts, s.MaxSizeCuts, s.EOFCuts)
+	for _, bucket := range s.Histogram() {
+		bar := strings.Repeat("#", int((bucket.Count*50+s.Count-1)/s.Count))
+		fmt.Fprintf(&b, "[%d, %d)\t%d\t%s\n", bucket.Low, bucket.High, bucket.Count, bar)
+	}
+	return b.String()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count       int64         `json:"count"`
+		Bytes       int64         `json:"bytes"`
+		Min         int           `json:"min"`
+		Max         int           `json:"max"`
+		Mean        float64       `json:"mean"`
+		StdDev      float64       `json:"stddev"`
+		ContentCuts int64         `json:"content_cuts"`
+		StrictCuts  int64         `json:"strict_cuts"`
+		LooseCuts   int64         `json:"loose_cuts"`
+		MaxSizeCuts int64         `json:"max_size_cuts"`
+		EOFCuts     int64         `json:"eof_cuts"`
+		Histogram   []StatsBucket `json:"histogram"`
+	}{s.Count, s.Bytes, s.Min, s.Max, s.Mean(), s.StdDev(), s.ContentCuts, s.StrictCuts, s.LooseCuts, s.MaxSizeCuts, s.EOFCuts, s.Histogram()})
+}
@@ -0,0 +1,109 @@
+package fastcdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSekienStats(t *testing.T) {
+	data := sekienData(t)
+	chunker, err := NewChunker(WithChunksSize(8192, 16384, 32768))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats Stats
+	var lengths []float64
+	for chunk, err := range stats.Collect(chunker.Chunks(bytes.NewReader(data))) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lengths = append(lengths, float64(len(chunk.Data)))
+	}
+
+	// 22366, 8282, 16303, 18696, 32768 and 11051 bytes.
+	if stats.Count != 6 || stats.Bytes != int64(len(data)) || stats.Min != 8282 || stats.Max != 32768 {
+		t.Errorf("want = {6 %d 8282 32768}, got = {%d %d %d %d}", len(data), stats.Count, stats.Bytes, stats.Min, stats.Max)
+	}
+	if stats.StrictCuts != 0 || stats.LooseCuts != 4 || stats.MaxSizeCuts != 1 || stats.EOFCuts != 1 {
+		t.Errorf("cuts: want = {0 4 1 1}, got = {%d %d %d %d}", stats.StrictCuts, stats.LooseCuts, stats.MaxSizeCuts, stats.EOFCuts)
+	}
+
+	wantHistogram := []StatsBucket{{8192, 16384, 3}, {16384, 32768, 2}, {32768, 65536, 1}}
+	if got := stats.Histogram(); len(got) != len(wantHistogram) || got[0] != wantHistogram[0] || got[1] != wantHistogram[1] || got[2] != wantHistogram[2] {
+		t.Errorf("histogram: want = %v, got = %v", wantHistogram, got)
+	}
+
+	var mean, variance float64
+	for _, n := range lengths {
+		mean += n / float64(len(lengths))
+	}
+	for _, n := range lengths {
+		variance += (n - mean) * (n - mean) / float64(len(lengths))
+	}
+	if math.Abs(stats.Mean()-mean) > 1e-6 || math.Abs(stats.StdDev()-math.Sqrt(variance)) > 1e-6 {
+		t.Errorf("mean and stddev: want = %f %f, got = %f %f", mean, math.Sqrt(variance), stats.Mean(), stats.StdDev())
+	}
+
+	b, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Count       int64         `json:"count"`
+		MaxSizeCuts int64         `json:"max_size_cuts"`
+		Histogram   []StatsBucket `json:"histogram"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Count != 6 || decoded.MaxSizeCuts != 1 || len(decoded.Histogram) != 3 {
+		t.Errorf("json: got = %s", b)
+	}
+
+	if text := stats.String(); !strings.Contains(text, "0 strict, 4 loose, 1 max size, 1 end of stream") {
+		t.Errorf("text: got = %s", text)
+	}
+}
+
+func TestStatsAllZeros(t *testing.T) {
+	input := make([]byte, 10240)
+	chunker, err := NewChunker(WithChunksSize(64, 256, 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats Stats
+	for _, err := range stats.Collect(chunker.Chunks(bytes.NewReader(input))) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats.MaxSizeCuts != 10 || stats.StrictCuts+stats.LooseCuts != 0 || stats.EOFCuts != 0 {
+		t.Errorf("cuts: want = {0 0 10 0}, got = {%d %d %d %d}", stats.StrictCuts, stats.LooseCuts, stats.MaxSizeCuts, stats.EOFCuts)
+	}
+}
+
+func TestStatsBreak(t *testing.T) {
+	data := sekienData(t)
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats Stats
+	for range stats.Collect(chunker.Chunks(bytes.NewReader(data))) {
+		break
+	}
+	if stats.Count != 1 || stats.Bytes != 22366 {
+		t.Errorf("want = {1 22366}, got = {%d %d}", stats.Count, stats.Bytes)
+	}
+
+	var empty Stats
+	if empty.StdDev() != 0 || len(empty.Histogram()) != 0 {
+		t.Error("empty statistics must be zero")
+	}
+}
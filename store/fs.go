@@ -0,0 +1,108 @@
+package store
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// FS is a ChunkStore backed by a directory. Every chunk is a file named
+// after its digest, in a sub-directory named after the first byte of the
+// digest. Chunks are written to a temporary file and renamed, so a chunk
+// file is either complete or missing.
+type FS struct {
+	root *os.Root
+}
+
+// NewFS returns a store in the given directory, which is created if it
+// does not exist. The store must be closed to release the directory.
+func NewFS(dir string) (*FS, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{root: root}, nil
+}
+
+// Close releases the directory.
+func (s *FS) Close() error {
+	return s.root.Close()
+}
+
+// Has reports whether the chunk is stored.
+func (s *FS) Has(d Digest) (bool, error) {
+	_, err := s.root.Stat(chunkPath(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get returns the content of the chunk, or ErrNotFound.
+func (s *FS) Get(d Digest) ([]byte, error) {
+	data, err := s.root.ReadFile(chunkPath(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put writes the chunk content to its file. A chunk file which already
+// exists is left untouched.
+func (s *FS) Put(d Digest, data []byte) error {
+	name := chunkPath(d)
+	if ok, err := s.Has(d); err != nil || ok {
+		return err
+	}
+	if err := s.root.MkdirAll(path.Dir(name), 0o750); err != nil {
+		return err
+	}
+
+	tmp := path.Join(path.Dir(name), ".tmp-"+rand.Text())
+	f, err := s.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = s.root.Rename(tmp, name)
+	}
+	if err != nil {
+		_ = s.root.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Delete removes the chunk file, or returns ErrNotFound.
+func (s *FS) Delete(d Digest) error {
+	err := s.root.Remove(chunkPath(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// chunkPath returns the path of the chunk file, relative to the store
+// directory.
+func chunkPath(d Digest) string {
+	name := d.String()
+	return path.Join(name[:2], name)
+}
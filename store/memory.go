@@ -0,0 +1,65 @@
+package store
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Memory is an in-memory ChunkStore.
+type Memory struct {
+	mu     sync.RWMutex
+	chunks map[Digest][]byte
+}
+
+// NewMemory returns an empty in-memory store.
+func NewMemory() *Memory {
+	return &Memory{chunks: make(map[Digest][]byte)}
+}
+
+// Has reports whether the chunk is stored.
+func (m *Memory) Has(d Digest) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.chunks[d]
+	return ok, nil
+}
+
+// Get returns the content of the chunk, or ErrNotFound. The returned
+// slice must not be modified.
+func (m *Memory) Get(d Digest) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.chunks[d]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Put stores a copy of the chunk content.
+func (m *Memory) Put(d Digest, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chunks[d]; !ok {
+		m.chunks[d] = bytes.Clone(data)
+	}
+	return nil
+}
+
+// Delete removes the chunk, or returns ErrNotFound.
+func (m *Memory) Delete(d Digest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.chunks[d]; !ok {
+		return ErrNotFound
+	}
+	delete(m.chunks, d)
+	return nil
+}
+
+// Len returns the number of stored chunks.
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.chunks)
+}
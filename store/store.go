@@ -0,0 +1,93 @@
+// Package store implements a content-addressed store for the chunks produced by a fastcdc.Splitter. Chunks are addressed
+// by the SHA-256 digest of their content, so a chunk seen twice is stored once.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+var (
+	ErrNotFound      = errors.New("chunk not found")
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// Digest is the SHA-256 digest of a chunk content.
+type Digest [sha256.Size]byte
+
+// Sum returns the digest of data.
+func Sum(data []byte) Digest {
+	return sha256.Sum256(data)
+}
+
+// ParseDigest parses the hexadecimal representation of a digest.
+func ParseDigest(s string) (Digest, error) {
+	var d Digest
+	if hex.DecodedLen(len(s)) != len(d) {
+		return Digest{}, fmt.Errorf("the digest must be %d hexadecimal characters long: %w", hex.EncodedLen(len(d)), ErrInvalidDigest)
+	}
+	if _, err := hex.Decode(d[:], []byte(s)); err != nil {
+		return Digest{}, fmt.Errorf("%w: %w", ErrInvalidDigest, err)
+	}
+	return d, nil
+}
+
+// String returns the hexadecimal representation of the digest.
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+// ChunkStore stores chunks by digest. Implementations must be safe for
+// concurrent use.
+type ChunkStore interface {
+	// Has reports whether the chunk is stored.
+	Has(d Digest) (bool, error)
+	// Get returns the content of the chunk, or ErrNotFound.
+	Get(d Digest) ([]byte, error)
+	// Put stores the chunk content under its digest. The store must not
+	// retain data. Storing a chunk which is already stored is a no-op.
+	Put(d Digest, data []byte) error
+	// Delete removes the chunk, or returns ErrNotFound.
+	Delete(d Digest) error
+}
+
+// ChunkRef is the reference of a chunk of a stream.
+type ChunkRef struct {
+	// Digest is the digest of the chunk content.
+	Digest Digest
+	// Offset is the position of the chunk in the stream.
+	Offset int64
+	// Length is the size of the chunk.
+	Length int
+}
+
+// StoreStream chunks the stream read from r with c, stores the chunks
+// which are not already in s and returns the ordered list of the chunk
+// references of the stream. On error, it returns the references of the
+// chunks stored so far along with the error.
+func StoreStream(c fastcdc.Splitter, r io.Reader, s ChunkStore) ([]ChunkRef, error) {
+	var refs []ChunkRef
+	for chunk, err := range c.Chunks(r) {
+		if err != nil {
+			return refs, err
+		}
+
+		d := Sum(chunk.Data)
+		ok, err := s.Has(d)
+		if err != nil {
+			return refs, err
+		}
+		if !ok {
+			if err := s.Put(d, chunk.Data); err != nil {
+				return refs, err
+			}
+		}
+		refs = append(refs, ChunkRef{Digest: d, Offset: chunk.Offset, Length: len(chunk.Data)})
+	}
+	return refs, nil
+}
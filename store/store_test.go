@@ -0,0 +1,232 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+func sekienData(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../fixtures/SekienAkashita.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newStores(t *testing.T) map[string]ChunkStore {
+	t.Helper()
+	fsStore, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fsStore.Close() })
+
+	return map[string]ChunkStore{
+		"memory": NewMemory(),
+		"fs":     fsStore,
+	}
+}
+
+func TestChunkStore(t *testing.T) {
+	for name, s := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("hello, world")
+			d := Sum(data)
+
+			if ok, err := s.Has(d); err != nil || ok {
+				t.Fatalf("has: want = false, got = %t, err = %v", ok, err)
+			}
+			if _, err := s.Get(d); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("get: want = %s, got = %s", ErrNotFound, err)
+			}
+			if err := s.Delete(d); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("delete: want = %s, got = %s", ErrNotFound, err)
+			}
+
+			if err := s.Put(d, data); err != nil {
+				t.Fatal(err)
+			}
+			// The store must not retain the data.
+			data[0] = 'j'
+			if err := s.Put(d, []byte("hello, world")); err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, err := s.Has(d); err != nil || !ok {
+				t.Fatalf("has: want = true, got = %t, err = %v", ok, err)
+			}
+			got, err := s.Get(d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "hello, world" {
+				t.Errorf("get: want = hello, world, got = %s", got)
+			}
+
+			if err := s.Delete(d); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := s.Has(d); err != nil || ok {
+				t.Fatalf("has after delete: want = false, got = %t, err = %v", ok, err)
+			}
+		})
+	}
+}
+
+// TestFSPutExisting checks that storing a chunk which is already stored
+// does not write its file again.
+func TestFSPutExisting(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	data := []byte("hello, world")
+	d := Sum(data)
+	if err := s.Put(d, data); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, filepath.FromSlash(chunkPath(d)))
+	before, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(d, data); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("the chunk file was replaced")
+	}
+}
+
+func TestStoreStream(t *testing.T) {
+	data := sekienData(t)
+	// The second copy of the data is made of the same chunks.
+	stream := append(bytes.Clone(data), data...)
+
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			refs, err := StoreStream(chunker, bytes.NewReader(stream), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pos int64
+			unique := make(map[Digest]struct{})
+			for _, ref := range refs {
+				if ref.Offset != pos {
+					t.Fatalf("offset: want = %d, got = %d", pos, ref.Offset)
+				}
+				got, err := s.Get(ref.Digest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, stream[ref.Offset:ref.Offset+int64(ref.Length)]) {
+					t.Fatalf("chunk content mismatch at offset %d", ref.Offset)
+				}
+				unique[ref.Digest] = struct{}{}
+				pos += int64(ref.Length)
+			}
+			if pos != int64(len(stream)) {
+				t.Fatalf("stream coverage: want = %d bytes, got = %d", len(stream), pos)
+			}
+			if len(unique) >= len(refs) {
+				t.Errorf("unique chunks: want < %d, got = %d", len(refs), len(unique))
+			}
+		})
+	}
+}
+
+func TestStoreStreamDedup(t *testing.T) {
+	data := sekienData(t)
+
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewMemory()
+	first, err := StoreStream(chunker, bytes.NewReader(data), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := s.Len()
+
+	second, err := StoreStream(chunker, bytes.NewReader(data), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != n {
+		t.Errorf("stored chunks: want = %d, got = %d", n, s.Len())
+	}
+	if len(first) != len(second) {
+		t.Fatalf("refs: want = %d, got = %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("ref %d: want = %v, got = %v", i, first[i], second[i])
+		}
+	}
+}
+
+// failingStore fails every Put.
+type failingStore struct {
+	*Memory
+	err error
+}
+
+func (f failingStore) Put(Digest, []byte) error {
+	return f.err
+}
+
+func TestStoreStreamError(t *testing.T) {
+	sentinel := errors.New("put failure")
+
+	chunker, err := fastcdc.NewChunker(fastcdc.With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := StoreStream(chunker, bytes.NewReader(sekienData(t)), failingStore{NewMemory(), sentinel})
+	if !errors.Is(err, sentinel) {
+		t.Errorf("want = %s, got = %s", sentinel, err)
+	}
+	if len(refs) != 0 {
+		t.Errorf("refs: want = 0, got = %d", len(refs))
+	}
+}
+
+func TestParseDigest(t *testing.T) {
+	d := Sum([]byte("foo"))
+	got, err := ParseDigest(d.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != d {
+		t.Errorf("want = %s, got = %s", d, got)
+	}
+
+	for _, s := range []string{"", "abc", d.String()[:62] + "zz", d.String() + "00"} {
+		if _, err := ParseDigest(s); !errors.Is(err, ErrInvalidDigest) {
+			t.Errorf("%q: want = %s, got = %s", s, ErrInvalidDigest, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package v1compat
+
+type Option func(*config)
+
+type config struct {
+	bufferSize uint
+	minSize    uint
+	avgSize    uint
+	maxSize    uint
+}
+
+// defaultConfig is the default configuration of v1, the 64k preset.
+func defaultConfig() *config {
+	return &config{
+		minSize: 32_768,
+		avgSize: 65_536,
+		maxSize: 131_072,
+	}
+}
+
+// WithBufferSize set the internal buffer size. It must be at least equal
+// to the max chunk size, and it is rounded up to a multiple of the max
+// chunk size, like in v1. Unlike the fastcdc.Chunker, the buffer size
+// determines the chunk boundaries: it must be the buffer size which v1 was
+// configured with.
+// Default is set to 2 * max size.
+func WithBufferSize(n uint) Option {
+	return func(c *config) {
+		c.bufferSize = n
+	}
+}
+
+// WithChunksSize set custom chunk size.
+func WithChunksSize(min, avg, max uint) Option {
+	return func(c *config) {
+		c.minSize = min
+		c.avgSize = avg
+		c.maxSize = max
+	}
+}
+
+// With16kChunks set the 16kb average chunks size preset of v1. Its
+// average size is 16_834, not 16_384.
+func With16kChunks() Option {
+	return func(c *config) {
+		c.minSize = 8192
+		c.avgSize = 16_834
+		c.maxSize = 32_768
+	}
+}
+
+// With32kChunks set the 32kb average chunks size preset of v1.
+func With32kChunks() Option {
+	return func(c *config) {
+		c.minSize = 16_384
+		c.avgSize = 32_768
+		c.maxSize = 65_536
+	}
+}
+
+// With64kChunks set the 64kb average chunks size preset of v1, which is
+// the default.
+func With64kChunks() Option {
+	return func(c *config) {
+		c.minSize = 32_768
+		c.avgSize = 65_536
+		c.maxSize = 131_072
+	}
+}
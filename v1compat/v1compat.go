@@ -0,0 +1,313 @@
+// Package v1compat reproduces the chunk boundaries of
+// github.com/tigerwill90/fastcdc v1.2.2, so that the indexes built with v1
+// stay valid without rechunking the archives.
+//
+// The v1 chunker only looks for a cut point within the data of its
+// buffer. When the end of the buffer is reached without cut point, the
+// bytes left are carried over to the next buffer, where the search resumes
+// on the new data with a fresh hash and the chunk sizes reduced by the
+// carry. The chunk boundaries therefore depend on the buffer size, which
+// must be the one v1 was configured with.
+//
+// The read pattern of the reader has no impact on the chunks: the buffer
+// is always filled before the search, like v1 does in stream mode, and in
+// regular mode with readers which fill the buffer, like *os.File or
+// *bytes.Reader.
+package v1compat
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"sync/atomic"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+var _ fastcdc.Splitter = (*Chunker)(nil)
+
+// Chunker splits a stream into the chunks of the v1 chunker.
+type Chunker struct {
+	buffer  []byte
+	minSize uint
+	avgSize uint
+	maxSize uint
+	maskS   uint64
+	maskL   uint64
+	busy    atomic.Bool
+}
+
+// NewChunker returns a chunker with the chunk boundaries of v1. It
+// enforces the chunk size bounds of fastcdc.NewChunker, which are those of
+// v1.
+func NewChunker(opts ...Option) (*Chunker, error) {
+	config := defaultConfig()
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.bufferSize == 0 {
+		config.bufferSize = 2 * config.maxSize
+	}
+
+	const (
+		errMinMsg = "chunk size must be at least"
+		errMaxMsg = "chunk size must be equal or lesser than"
+	)
+
+	if config.minSize < fastcdc.MinimumMin {
+		return nil, fmt.Errorf("the minimum %s %d: %w", errMinMsg, fastcdc.MinimumMin, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.minSize > fastcdc.MinimumMax {
+		return nil, fmt.Errorf("the minimum %s %d: %w", errMaxMsg, fastcdc.MinimumMax, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.avgSize < fastcdc.AverageMin {
+		return nil, fmt.Errorf("the average %s %d: %w", errMinMsg, fastcdc.AverageMin, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.avgSize > fastcdc.AverageMax {
+		return nil, fmt.Errorf("the average %s %d: %w", errMaxMsg, fastcdc.AverageMax, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.maxSize < fastcdc.MaximumMin {
+		return nil, fmt.Errorf("the maximum %s %d: %w", errMinMsg, fastcdc.MaximumMin, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.maxSize > fastcdc.MaximumMax {
+		return nil, fmt.Errorf("the maximum %s %d: %w", errMaxMsg, fastcdc.MaximumMax, fastcdc.ErrInvalidChunkSize)
+	}
+	if config.bufferSize < config.maxSize {
+		return nil, fmt.Errorf("the buffer size must be greater or equal than the maximum chunk size (%d): %w", config.maxSize, fastcdc.ErrInvalidBufferSize)
+	}
+	if config.minSize >= config.avgSize {
+		return nil, fmt.Errorf("the minimum chunk size must be smaller than the average: %w", fastcdc.ErrInvalidChunkSize)
+	}
+	if config.maxSize <= config.avgSize {
+		return nil, fmt.Errorf("the maximum chunk size must be bigger than the average: %w", fastcdc.ErrInvalidChunkSize)
+	}
+	if config.maxSize-config.minSize <= config.avgSize {
+		return nil, fmt.Errorf("maximum - minimum chunk size must be bigger than the average chunk size: %w", fastcdc.ErrInvalidChunkSize)
+	}
+
+	// v1 rounds the buffer size up to a multiple of the max size.
+	bufferSize := config.bufferSize
+	if remaining := bufferSize % config.maxSize; remaining != 0 {
+		bufferSize += config.maxSize - remaining
+	}
+
+	bits := logarithm2(config.avgSize)
+	return &Chunker{
+		buffer:  make([]byte, bufferSize),
+		minSize: config.minSize,
+		avgSize: config.avgSize,
+		maxSize: config.maxSize,
+		maskS:   mask(bits + 1),
+		maskL:   mask(bits - 1),
+	}, nil
+}
+
+// Chunks returns an iterator that yields the chunks of the stream read
+// from r in order, with the boundaries of v1. Every chunk size is within
+// [min, max], except the last chunk of the stream which can be smaller
+// than min. On a read error, the iterator yields a zero Chunk with the
+// error and stops.
+//
+// The yielded Chunk.Data aliases the chunker's internal buffer. It is only
+// valid for the current iteration and must be copied for later use.
+// Chunk.Sum is always nil.
+//
+// The chunker can be reused for another stream once the previous
+// iteration is over, but only one iteration must run at a time.
+func (c *Chunker) Chunks(r io.Reader) iter.Seq2[fastcdc.Chunk, error] {
+	return func(yield func(fastcdc.Chunk, error) bool) {
+		if !c.busy.CompareAndSwap(false, true) {
+			panic("v1compat: chunker already in use")
+		}
+		defer c.busy.Store(false)
+
+		var (
+			offset int64 // stream position of the next chunk
+			carry  uint  // bytes left at the front of the buffer
+		)
+		for {
+			// Fill the buffer after the carry.
+			end := carry
+			eof := false
+			for end < uint(len(c.buffer)) {
+				n, err := r.Read(c.buffer[end:])
+				end += uint(n)
+				if err == io.EOF {
+					eof = true
+					break
+				}
+				if err != nil {
+					yield(fastcdc.Chunk{}, err)
+					return
+				}
+			}
+
+			// Find the chunks of the new data. A chunk which started in
+			// the previous buffer includes the carry.
+			start := carry
+			for start < end {
+				length, reason := c.breakpoint(c.buffer[start:end], carry)
+				if length == 0 {
+					// No cut point until the end of the buffer: carry the
+					// bytes left over to the next one.
+					copy(c.buffer[carry:], c.buffer[start:end])
+					carry += end - start
+					break
+				}
+				data := c.buffer[start-carry : start+length]
+				if !yield(fastcdc.Chunk{Offset: offset, Data: data, Reason: reason}, nil) {
+					return
+				}
+				offset += int64(len(data))
+				start += length
+				carry = 0
+			}
+
+			if eof {
+				if carry > 0 {
+					yield(fastcdc.Chunk{Offset: offset, Data: c.buffer[:carry], Reason: fastcdc.CutEOF}, nil)
+				}
+				return
+			}
+		}
+	}
+}
+
+// breakpoint is the breakpoint of v1. The chunk sizes are reduced by the
+// carry, and the hash only covers the bytes after it.
+func (c *Chunker) breakpoint(window []byte, carry uint) (uint, fastcdc.CutReason) {
+	minSize := reduce(c.minSize, carry)
+	avgSize := reduce(c.avgSize, carry)
+	maxSize := reduce(c.maxSize, carry)
+
+	length := uint(len(window))
+
+	// Sub-minimum chunk cut-point skipping.
+	if length <= minSize {
+		return 0, fastcdc.CutNone
+	}
+
+	if length > maxSize {
+		length = maxSize
+	}
+
+	normalSize := centerSize(avgSize, minSize, length)
+
+	var hash uint64
+	cut := minSize
+
+	for cut < normalSize {
+		hash = (hash >> 1) + table[window[cut]]
+		cut++
+		if hash&c.maskS == 0 {
+			return cut, fastcdc.CutStrict
+		}
+	}
+
+	for cut < length {
+		hash = (hash >> 1) + table[window[cut]]
+		cut++
+		if hash&c.maskL == 0 {
+			return cut, fastcdc.CutLoose
+		}
+	}
+
+	// The reduced max size also ensures that the carry never grows over
+	// the max size.
+	if cut == maxSize {
+		return cut, fastcdc.CutMaxSize
+	}
+	return 0, fastcdc.CutNone
+}
+
+// reduce reduces a chunk size by the carry, down to 1.
+func reduce(size, carry uint) uint {
+	if carry < size {
+		return size - carry
+	}
+	return 1
+}
+
+// centerSize is the normal size of v1, like ronomon/deduplication.
+func centerSize(average, minimum, sourceSize uint) uint {
+	offset := minimum + ceilDiv(minimum, 2)
+	if offset > average {
+		offset = average
+	}
+	size := average - offset
+	if size > sourceSize {
+		return sourceSize
+	}
+	return size
+}
+
+// Integer division that rounds up instead of down.
+func ceilDiv(x, y uint) uint {
+	return (x + y - 1) / y
+}
+
+func mask(bits uint) uint64 {
+	if bits < 1 {
+		panic("bits too low")
+	}
+	if bits > 31 {
+		panic("bits too high")
+	}
+	return 1<<bits - 1
+}
+
+// Base 2 logarithm, rounded to the nearest integer.
+func logarithm2(value uint) uint {
+	return uint(math.Round(math.Log2(float64(value))))
+}
+
+// table is the gear table of v1, which is also the built-in gear table of
+// fastcdc.
+var table = [256]uint64{
+	1553318008, 574654857, 759734804, 310648967, 1393527547, 1195718329,
+	694400241, 1154184075, 1319583805, 1298164590, 122602963, 989043992,
+	1918895050, 933636724, 1369634190, 1963341198, 1565176104, 1296753019,
+	1105746212, 1191982839, 1195494369, 29065008, 1635524067, 722221599,
+	1355059059, 564669751, 1620421856, 1100048288, 1018120624, 1087284781,
+	1723604070, 1415454125, 737834957, 1854265892, 1605418437, 1697446953,
+	973791659, 674750707, 1669838606, 320299026, 1130545851, 1725494449,
+	939321396, 748475270, 554975894, 1651665064, 1695413559, 671470969,
+	992078781, 1935142196, 1062778243, 1901125066, 1935811166, 1644847216,
+	744420649, 2068980838, 1988851904, 1263854878, 1979320293, 111370182,
+	817303588, 478553825, 694867320, 685227566, 345022554, 2095989693,
+	1770739427, 165413158, 1322704750, 46251975, 710520147, 700507188,
+	2104251000, 1350123687, 1593227923, 1756802846, 1179873910, 1629210470,
+	358373501, 807118919, 751426983, 172199468, 174707988, 1951167187,
+	1328704411, 2129871494, 1242495143, 1793093310, 1721521010, 306195915,
+	1609230749, 1992815783, 1790818204, 234528824, 551692332, 1930351755,
+	110996527, 378457918, 638641695, 743517326, 368806918, 1583529078,
+	1767199029, 182158924, 1114175764, 882553770, 552467890, 1366456705,
+	934589400, 1574008098, 1798094820, 1548210079, 821697741, 601807702,
+	332526858, 1693310695, 136360183, 1189114632, 506273277, 397438002,
+	620771032, 676183860, 1747529440, 909035644, 142389739, 1991534368,
+	272707803, 1905681287, 1210958911, 596176677, 1380009185, 1153270606,
+	1150188963, 1067903737, 1020928348, 978324723, 962376754, 1368724127,
+	1133797255, 1367747748, 1458212849, 537933020, 1295159285, 2104731913,
+	1647629177, 1691336604, 922114202, 170715530, 1608833393, 62657989,
+	1140989235, 381784875, 928003604, 449509021, 1057208185, 1239816707,
+	525522922, 476962140, 102897870, 132620570, 419788154, 2095057491,
+	1240747817, 1271689397, 973007445, 1380110056, 1021668229, 12064370,
+	1186917580, 1017163094, 597085928, 2018803520, 1795688603, 1722115921,
+	2015264326, 506263638, 1002517905, 1229603330, 1376031959, 763839898,
+	1970623926, 1109937345, 524780807, 1976131071, 905940439, 1313298413,
+	772929676, 1578848328, 1108240025, 577439381, 1293318580, 1512203375,
+	371003697, 308046041, 320070446, 1252546340, 568098497, 1341794814,
+	1922466690, 480833267, 1060838440, 969079660, 1836468543, 2049091118,
+	2023431210, 383830867, 2112679659, 231203270, 1551220541, 1377927987,
+	275637462, 2110145570, 1700335604, 738389040, 1688841319, 1506456297,
+	1243730675, 258043479, 599084776, 41093802, 792486733, 1897397356,
+	28077829, 1520357900, 361516586, 1119263216, 209458355, 45979201,
+	363681532, 477245280, 2107748241, 601938891, 244572459, 1689418013,
+	1141711990, 1485744349, 1181066840, 1950794776, 410494836, 1445347454,
+	2137242950, 852679640, 1014566730, 1999335993, 1871390758, 1736439305,
+	231222289, 603972436, 783045542, 370384393, 184356284, 709706295,
+	1453549767, 591603172, 768512391, 854125182,
+}
@@ -0,0 +1,216 @@
+package v1compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tigerwill90/fastcdc/v2"
+)
+
+type v1Vector struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Input   string `json:"input"`
+	Min     uint   `json:"min"`
+	Avg     uint   `json:"avg"`
+	Max     uint   `json:"max"`
+	Buffer  uint   `json:"buffer"`
+	Lengths []int  `json:"lengths"`
+}
+
+type chunkInfo struct {
+	Offset int64
+	Length int
+}
+
+// chunkyReader delivers at most n bytes per read.
+type chunkyReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *chunkyReader) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.r.Read(p)
+}
+
+func loadVectors(t *testing.T) []v1Vector {
+	t.Helper()
+	b, err := os.ReadFile("../testdata/v1/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []v1Vector
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+// chunkAll collects the chunks of r and checks that they cover data.
+func chunkAll(t *testing.T, c *Chunker, r io.Reader, data []byte) []chunkInfo {
+	t.Helper()
+	var chunks []chunkInfo
+	var offset int64
+	for chunk, err := range c.Chunks(r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Offset != offset {
+			t.Fatalf("offset: want = %d, got = %d", offset, chunk.Offset)
+		}
+		if !bytes.Equal(chunk.Data, data[offset:offset+int64(len(chunk.Data))]) {
+			t.Fatalf("chunk at offset %d: data mismatch", offset)
+		}
+		chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+		offset += int64(len(chunk.Data))
+	}
+	if offset != int64(len(data)) {
+		t.Fatalf("chunks cover %d bytes of %d", offset, len(data))
+	}
+	return chunks
+}
+
+// TestVectors checks the chunks against the vectors of testdata/v1,
+// whatever the read pattern of the reader.
+func TestVectors(t *testing.T) {
+	readers := map[string]func(io.Reader) io.Reader{
+		"full":     func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"data err": iotest.DataErrReader,
+		"7 bytes":  func(r io.Reader) io.Reader { return &chunkyReader{r, 7} },
+	}
+
+	for _, v := range loadVectors(t) {
+		t.Run(v.Name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("..", v.Input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []chunkInfo
+			var offset int64
+			for _, n := range v.Lengths {
+				want = append(want, chunkInfo{offset, n})
+				offset += int64(n)
+			}
+			if offset != int64(len(data)) {
+				t.Fatalf("the vector covers %d bytes of %d", offset, len(data))
+			}
+
+			opts := []Option{WithChunksSize(v.Min, v.Avg, v.Max)}
+			if v.Buffer != 0 {
+				opts = append(opts, WithBufferSize(v.Buffer))
+			}
+			chunker, err := NewChunker(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, wrap := range readers {
+				if got := chunkAll(t, chunker, wrap(bytes.NewReader(data)), data); !slices.Equal(got, want) {
+					t.Errorf("%s reader: chunks: want = %v, got = %v", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+// TestBufferSize checks that the chunks depend on the buffer size, like
+// in v1, and that the v2 chunker does not reproduce them.
+func TestBufferSize(t *testing.T) {
+	data, err := os.ReadFile("../testdata/ronomon/random.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := func(bufSize uint) []chunkInfo {
+		chunker, err := NewChunker(WithChunksSize(64, 256, 1024), WithBufferSize(bufSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chunkAll(t, chunker, bytes.NewReader(data), data)
+	}
+
+	// The buffer size is rounded up to a multiple of the max size.
+	if a, b := chunks(1500), chunks(2048); !slices.Equal(a, b) {
+		t.Errorf("buffer size 1500: want = %v, got = %v", b, a)
+	}
+	if a, b := chunks(1024), chunks(2048); slices.Equal(a, b) {
+		t.Error("buffer sizes 1024 and 2048: same chunks")
+	}
+
+	chunker, err := fastcdc.NewChunker(fastcdc.WithChunksSize(64, 256, 1024), fastcdc.WithBufferSize(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v2 []chunkInfo
+	for chunk := range chunker.ChunkBytes(data) {
+		v2 = append(v2, chunkInfo{chunk.Offset, len(chunk.Data)})
+	}
+	if slices.Equal(v2, chunks(1024)) {
+		t.Error("v2: same chunks as v1")
+	}
+}
+
+func TestChunkSizes(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		for i, n := range v.Lengths[:len(v.Lengths)-1] {
+			if uint(n) < v.Min || uint(n) > v.Max {
+				t.Errorf("%s: chunk %d: size %d out of [%d, %d]", v.Name, i, n, v.Min, v.Max)
+			}
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	tests := map[string]struct {
+		Opts []Option
+		Want error
+	}{
+		"default":           {nil, nil},
+		"16k preset":        {[]Option{With16kChunks()}, nil},
+		"32k preset":        {[]Option{With32kChunks()}, nil},
+		"64k preset":        {[]Option{With64kChunks()}, nil},
+		"min size":          {[]Option{WithChunksSize(fastcdc.MinimumMin-1, 256, 1024)}, fastcdc.ErrInvalidChunkSize},
+		"avg size":          {[]Option{WithChunksSize(64, fastcdc.AverageMin-1, 1024)}, fastcdc.ErrInvalidChunkSize},
+		"max size":          {[]Option{WithChunksSize(64, 256, fastcdc.MaximumMin-1)}, fastcdc.ErrInvalidChunkSize},
+		"min over avg":      {[]Option{WithChunksSize(512, 256, 1024)}, fastcdc.ErrInvalidChunkSize},
+		"avg over max":      {[]Option{WithChunksSize(64, 2048, 1024)}, fastcdc.ErrInvalidChunkSize},
+		"proportional":      {[]Option{WithChunksSize(1024, 2048, 3072)}, fastcdc.ErrInvalidChunkSize},
+		"small buffer size": {[]Option{WithChunksSize(64, 256, 1024), WithBufferSize(1023)}, fastcdc.ErrInvalidBufferSize},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewChunker(tc.Opts...)
+			if !errors.Is(err, tc.Want) {
+				t.Errorf("want = %v, got = %v", tc.Want, err)
+			}
+		})
+	}
+}
+
+func TestReadError(t *testing.T) {
+	chunker, err := NewChunker(WithChunksSize(64, 256, 1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := errors.New("read error")
+	for chunk, err := range chunker.Chunks(iotest.ErrReader(want)) {
+		if !errors.Is(err, want) {
+			t.Fatalf("error: want = %v, got = %v", want, err)
+		}
+		if chunk.Data != nil {
+			t.Errorf("chunk: want = zero, got = %v", chunk)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package fastcdc
+
+// Writer is a push-mode chunker. The stream is written to it in any
+// pattern and its chunks are delivered in order to a callback. A Writer
+// produces exactly the same chunks as Chunker.Chunks for the same stream.
+type Writer struct {
+	c      *Chunker
+	fn     func(Chunk) error
+	offset int64 // stream position of buffer[0]
+	start  uint  // start of the current chunk in the buffer
+	end    uint  // end of the buffered data
+	err    error
+	closed bool
+}
+
+// NewWriter returns a Writer that splits the stream written to it and
+// calls fn for every chunk, in order. If fn returns an error, the
+// Writer stops and the error is returned by the current and subsequent
+// calls to Write and by Close.
+//
+// The Chunk.Data and Chunk.Sum passed to fn alias the chunker's internal
+// buffers. They are only valid for the duration of the call and must be
+// copied for later use.
+//
+// The Writer holds the chunker until it is closed. Close must always be
+// called to flush the last chunk and release the chunker, and no other
+// iteration or Writer must run on the same chunker in the meantime.
+func (c *Chunker) NewWriter(fn func(Chunk) error) *Writer {
+	if !c.busy.CompareAndSwap(false, true) {
+		panic("fastcdc: chunker already in use")
+	}
+	return &Writer{c: c, fn: fn}
+}
+
+// Write buffers p and delivers every chunk that can be cut with at least
+// max size bytes ahead. The chunks that depend on the bytes yet to come
+// are held until the next Write or Close.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	var n int
+	for len(p) > 0 {
+		if w.end == uint(len(w.c.buffer)) {
+			// The buffer always keeps less than max size pending bytes
+			// once the chunks are delivered, so moving them at the front
+			// of the buffer makes room for the new data.
+			copy(w.c.buffer, w.c.buffer[w.start:w.end])
+			w.offset += int64(w.start)
+			w.end -= w.start
+			w.start = 0
+		}
+
+		m := copy(w.c.buffer[w.end:], p)
+		w.end += uint(m)
+		p = p[m:]
+		n += m
+
+		// Like Chunks, only look for a cut point with at least max size
+		// bytes ahead, so the write pattern cannot influence the chunk
+		// boundaries.
+		for w.end-w.start >= w.c.maxSize {
+			if err := w.emit(w.c.nextCut(w.c.buffer[w.start:w.end])); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close delivers the remaining chunks, including the last chunk of the
+// stream which can be smaller than min, and releases the chunker. It
+// returns the error of the callback, if any. Calling Close more than once
+// has no further effect.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	defer w.c.busy.Store(false)
+
+	for w.err == nil && w.start < w.end {
+		_ = w.emit(w.c.nextCut(w.c.buffer[w.start:w.end]))
+	}
+	return w.err
+}
+
+// emit delivers the next chunk of the given length to the callback.
+func (w *Writer) emit(length uint, reason CutReason) error {
+	data := w.c.buffer[w.start : w.start+length]
+	chunk := Chunk{Offset: w.offset + int64(w.start), Data: data, Sum: w.c.digester.digest(data), Reason: reason}
+	w.start += length
+	if err := w.fn(chunk); err != nil {
+		w.err = err
+	}
+	return w.err
+}
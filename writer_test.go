@@ -0,0 +1,197 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeAll writes input to a new Writer of the chunker in writes of at
+// most frag bytes and verifies that the chunks are contiguous and match
+// the input content.
+func writeAll(t *testing.T, chunker *Chunker, input []byte, frag int) []chunkInfo {
+	t.Helper()
+	var chunks []chunkInfo
+	var pos int64
+	w := chunker.NewWriter(func(chunk Chunk) error {
+		if chunk.Offset != pos {
+			t.Fatalf("offset: want = %d, got = %d", pos, chunk.Offset)
+		}
+		if !bytes.Equal(chunk.Data, input[chunk.Offset:chunk.Offset+int64(len(chunk.Data))]) {
+			t.Fatalf("chunk content mismatch at offset %d", chunk.Offset)
+		}
+		chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+		pos += int64(len(chunk.Data))
+		return nil
+	})
+
+	for p := input; len(p) > 0; {
+		n := min(frag, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pos != int64(len(input)) {
+		t.Fatalf("stream coverage: want = %d bytes, got = %d", len(input), pos)
+	}
+	return chunks
+}
+
+func TestSekienWriter(t *testing.T) {
+	data := sekienData(t)
+
+	for name, tc := range sekienGoldens {
+		t.Run(name, func(t *testing.T) {
+			chunker, err := NewChunker(tc.Preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, frag := range []int{1, 7, 1000, 4096, len(data)} {
+				chunks := writeAll(t, chunker, data, frag)
+				if !slices.Equal(chunks, tc.Want) {
+					t.Errorf("%d bytes writes: chunks: want = %v, got = %v", frag, tc.Want, chunks)
+				}
+			}
+		})
+	}
+}
+
+// TestWriterRandomInput checks on random input that the Writer produces
+// the same chunks as Chunks, whatever the buffer size and the write
+// pattern.
+func TestWriterRandomInput(t *testing.T) {
+	seed := time.Now().UnixNano()
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	t.Logf("seed: %d", seed)
+
+	reference, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 50 {
+		size := rng.IntN(2 << 20)
+		data := randomData(rng.Uint64(), size)
+		want := chunkAll(t, reference, bytes.NewReader(data), data)
+
+		bufSize := 131_072 + uint(rng.IntN(1<<19))
+		frag := 1 + rng.IntN(1<<18)
+		chunker, err := NewChunker(With16kChunks(), WithBufferSize(bufSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := writeAll(t, chunker, data, frag)
+		if !slices.Equal(got, want) {
+			t.Fatalf("chunks: want = %v, got = %v, input size = %d, buffer size = %d, write size = %d", want, got, size, bufSize, frag)
+		}
+	}
+}
+
+func TestWriterCopy(t *testing.T) {
+	data := sekienData(t)
+	golden := sekienGoldens["16kChunks"]
+
+	chunker, err := NewChunker(golden.Preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []chunkInfo
+	w := chunker.NewWriter(func(chunk Chunk) error {
+		chunks = append(chunks, chunkInfo{chunk.Offset, len(chunk.Data)})
+		return nil
+	})
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(chunks, golden.Want) {
+		t.Errorf("chunks: want = %v, got = %v", golden.Want, chunks)
+	}
+}
+
+func TestWriterCallbackError(t *testing.T) {
+	sentinel := errors.New("callback failure")
+	data := randomData(42, 1<<20)
+
+	chunker, err := NewChunker(With16kChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	w := chunker.NewWriter(func(chunk Chunk) error {
+		calls++
+		return sentinel
+	})
+	if _, err := w.Write(data); !errors.Is(err, sentinel) {
+		t.Errorf("write: want = %s, got = %s", sentinel, err)
+	}
+	if _, err := w.Write(data); !errors.Is(err, sentinel) {
+		t.Errorf("second write: want = %s, got = %s", sentinel, err)
+	}
+	if err := w.Close(); !errors.Is(err, sentinel) {
+		t.Errorf("close: want = %s, got = %s", sentinel, err)
+	}
+	if calls != 1 {
+		t.Errorf("callback calls: want = 1, got = %d", calls)
+	}
+
+	// The chunker is released by Close.
+	chunkAll(t, chunker, bytes.NewReader(data), data)
+}
+
+func TestWriterClosed(t *testing.T) {
+	chunker, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := chunker.NewWriter(func(chunk Chunk) error {
+		t.Error("no chunk expected on empty input")
+		return nil
+	})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second close: want = nil, got = %s", err)
+	}
+	if _, err := w.Write([]byte("foo")); !errors.Is(err, ErrWriterClosed) {
+		t.Errorf("want = %s, got = %s", ErrWriterClosed, err)
+	}
+}
+
+func TestWriterInUsePanic(t *testing.T) {
+	chunker, err := NewChunker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := chunker.NewWriter(func(Chunk) error { return nil })
+	defer w.Close()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("the code did not panic")
+		} else {
+			panicMsg := r.(string)
+			want := "fastcdc: chunker already in use"
+			if panicMsg != want {
+				t.Errorf("want = %s, got = %s", want, r)
+			}
+		}
+	}()
+	for range chunker.Chunks(bytes.NewReader(nil)) {
+	}
+}